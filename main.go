@@ -1,29 +1,204 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"glox/src"
 	"os"
+	"strings"
 )
 
 func main() {
-	if len(os.Args) > 2 {
-		_, _ = fmt.Fprintln(os.Stderr, "[Main] Usage: glox [script]")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "lsp":
+			err := glox.NewLanguageServer(os.Stdin, os.Stdout).Serve()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, "[Lsp]", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case "dap":
+			err := glox.NewDebugAdapter(os.Stdin, os.Stdout).Serve()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, "[Dap]", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
+	typeCheck := flag.Bool("typecheck", false, "check types before running")
+	profile := flag.String("profile", "", "write a profile of the run to the file")
+	profileFormat := flag.String("profile-format", glox.ProfilePprof, "format of the profile, pprof or text")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "[Main] Usage: glox [--typecheck] [--profile=file [--profile-format=pprof|text]] [script]\n       glox fmt [--check | --write] file...\n       glox lint [--disable rule,...] file...\n       glox debug script\n       glox lsp\n       glox dap")
+	}
+	flag.Parse()
+	if flag.NArg() > 1 || *profileFormat != glox.ProfilePprof && *profileFormat != glox.ProfileText {
+		flag.Usage()
 		os.Exit(64)
 	}
 
 	loxInterpreter := glox.NewGlox()
+	if *typeCheck {
+		loxInterpreter.EnableTypeCheck()
+	}
+	if *profile != "" {
+		loxInterpreter.EnableProfiling()
+	}
 
-	if len(os.Args) == 2 {
-		code := loxInterpreter.RunFile(os.Args[1])
+	code := 0
+	if flag.NArg() == 1 {
+		code = loxInterpreter.RunFile(flag.Arg(0))
 		if code != 0 {
-			_, _ = fmt.Fprintln(os.Stderr, "[Main] Failed when running file", os.Args[1])
-			os.Exit(code)
+			_, _ = fmt.Fprintln(os.Stderr, "[Main] Failed when running file", flag.Arg(0))
 		}
 	} else {
-		code := loxInterpreter.RunPrompt()
-		if code != 0 {
-			os.Exit(code)
+		code = loxInterpreter.RunPrompt()
+	}
+	if *profile != "" && !writeProfile(loxInterpreter, *profile, *profileFormat) && code == 0 {
+		code = 1
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// writeProfile writes the profile of the runs to the file, and reports whether it succeeded.
+func writeProfile(loxInterpreter *glox.Glox, path string, format string) bool {
+	file, err := os.Create(path)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+		return false
+	}
+	err = loxInterpreter.WriteProfile(file, format)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "[Profile]", err)
+		return false
+	}
+	return true
+}
+
+// runFmt formats the files, printing the result unless --write replaces the
+// files with it. With --check, it only lists the files that aren't formatted.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list the files that aren't formatted and fail if there are any")
+	write := flags.Bool("write", false, "write the result to the files instead of printing it")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "[Fmt] Usage: glox fmt [--check | --write] file...")
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 || (*check && *write) {
+		flags.Usage()
+		return 64
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+			code = 1
+			continue
+		}
+		formatted, err := glox.NewFormatter().Format(string(source))
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "[Fmt]", path+":", err.Error())
+			code = 1
+			continue
+		}
+		switch {
+		case *check:
+			if formatted != string(source) {
+				fmt.Println(path)
+				code = 1
+			}
+		case *write:
+			if formatted != string(source) {
+				err = os.WriteFile(path, []byte(formatted), 0644)
+				if err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+					code = 1
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	return code
+}
+
+// runLint prints the warnings of the files, and fails if there are any.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	disable := flags.String("disable", "", "comma-separated rules to turn off, among "+strings.Join(glox.LintRules(), ", "))
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "[Lint] Usage: glox lint [--disable rule,...] file...")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 64
+	}
+	linter := glox.NewLinter()
+	if *disable != "" {
+		for _, rule := range strings.Split(*disable, ",") {
+			err := linter.Disable(strings.TrimSpace(rule))
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, "[Lint]", err.Error())
+				return 64
+			}
 		}
 	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+			code = 1
+			continue
+		}
+		warnings, err := linter.Lint(string(source))
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "[Lint]", path+":", err.Error())
+			code = 1
+			continue
+		}
+		for _, warning := range warnings {
+			fmt.Println(path, warning.String())
+			code = 1
+		}
+	}
+	return code
+}
+
+// runDebug debugs a script at a gdb-like prompt.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(os.Stderr, "[Debug] Usage: glox debug script")
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	if _, err := os.Stat(flags.Arg(0)); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+		return 1
+	}
+	return glox.NewDebugCLI(flags.Arg(0), os.Stdin, os.Stdout).Run()
 }
@@ -89,6 +89,10 @@ func (s *AstPrinter) visitAssignExpr(expr *Assign) (interface{}, error) {
 	return s.parenthesize2("=", expr.name.lexeme, expr.value)
 }
 
+func (s *AstPrinter) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	return s.parenthesize("=", expr.pattern, expr.value)
+}
+
 func (s *AstPrinter) visitBinaryExpr(expr *Binary) (interface{}, error) {
 	return s.parenthesize(expr.operator.lexeme, expr.left, expr.right)
 }
@@ -105,6 +109,10 @@ func (s *AstPrinter) visitGroupingExpr(expr *Grouping) (str interface{}, err err
 	return s.parenthesize("group", expr.expression)
 }
 
+func (s *AstPrinter) visitListLiteralExpr(expr *ListLiteral) (str interface{}, err error) {
+	return s.parenthesize("list", *expr.elements...)
+}
+
 func (s *AstPrinter) visitLiteralExpr(expr *Literal) (str interface{}, err error) {
 	if expr.value == nil {
 		return "nil", nil
@@ -119,10 +127,47 @@ func (s *AstPrinter) visitLogicalExpr(expr *Logical) (str interface{}, err error
 	return s.parenthesize(expr.operator.lexeme, expr.left, expr.right)
 }
 
+func (s *AstPrinter) visitMapLiteralExpr(expr *MapLiteral) (str interface{}, err error) {
+	res := "(map"
+	for i, key := range *expr.keys {
+		k, err := s.PrintExpression(key)
+		if err != nil {
+			return "", err
+		}
+		res += " " + k
+		if value := (*expr.values)[i]; value != nil {
+			v, err := s.PrintExpression(value)
+			if err != nil {
+				return "", err
+			}
+			res += ":" + v
+		}
+	}
+	res += ")"
+	return res, nil
+}
+
+func (s *AstPrinter) visitObjectPatternExpr(expr *ObjectPattern) (str interface{}, err error) {
+	res := "(object"
+	for i, key := range *expr.keys {
+		value, err := s.PrintExpression((*expr.values)[i])
+		if err != nil {
+			return "", err
+		}
+		res += " " + key.lexeme + ":" + value
+	}
+	res += ")"
+	return res, nil
+}
+
 func (s *AstPrinter) visitSetExpr(expr *Set) (str interface{}, err error) {
 	return s.parenthesize2("=", expr.object, expr.name.lexeme, expr.value)
 }
 
+func (s *AstPrinter) visitSpreadExpr(expr *Spread) (str interface{}, err error) {
+	return s.parenthesize("...", expr.expression)
+}
+
 func (s *AstPrinter) visitSuperExpr(expr *Super) (str interface{}, err error) {
 	return s.parenthesize2("super", expr.method)
 }
@@ -157,6 +202,9 @@ func (s *AstPrinter) visitBlockStmt(stmt *Block) (interface{}, error) {
 
 func (s *AstPrinter) visitClassStmt(stmt *Class) (interface{}, error) {
 	res := "(class " + stmt.name.lexeme
+	if stmt.abstract != nil {
+		res = "(abstract class " + stmt.name.lexeme
+	}
 	if stmt.superclass != nil {
 		res += " < "
 		str, err := s.PrintExpression(stmt.superclass)
@@ -165,6 +213,18 @@ func (s *AstPrinter) visitClassStmt(stmt *Class) (interface{}, error) {
 		}
 		res += str
 	}
+	for i, iface := range *stmt.interfaces {
+		if i == 0 {
+			res += " implements"
+		}
+		res += " " + iface.name.lexeme
+	}
+	for i, trait := range *stmt.traits {
+		if i == 0 {
+			res += " with"
+		}
+		res += " " + trait.name.lexeme
+	}
 	for _, method := range *stmt.methods {
 		res += " "
 		str, err := s.PrintStatement(method)
@@ -177,19 +237,42 @@ func (s *AstPrinter) visitClassStmt(stmt *Class) (interface{}, error) {
 	return res, nil
 }
 
+func (s *AstPrinter) visitConstStmt(stmt *Const) (interface{}, error) {
+	return s.parenthesize2("const", stmt.name, "=", stmt.initializer)
+}
+
+func (s *AstPrinter) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	res := "(enum " + stmt.name.lexeme
+	for _, member := range *stmt.members {
+		res += " " + member.lexeme
+	}
+	res += ")"
+	return res, nil
+}
+
 func (s *AstPrinter) visitExpressionStmt(stmt *Expression) (interface{}, error) {
 	return s.parenthesize(";", stmt.expression)
 }
 
 func (s *AstPrinter) visitFunctionStmt(stmt *Function) (interface{}, error) {
 	res := "(fun " + stmt.name.lexeme + " ("
-	for _, param := range *stmt.params {
+	for i, param := range *stmt.params {
 		if param != (*stmt.params)[0] {
 			res += " "
 		}
-		res += param.lexeme
+		res += param.lexeme + annotationString((*stmt.paramTypes)[i])
+	}
+	if stmt.rest != nil {
+		if len(*stmt.params) != 0 {
+			res += " "
+		}
+		res += "..." + stmt.rest.lexeme
 	}
-	res += ") "
+	res += ")" + annotationString(stmt.returnType)
+	if stmt.body == nil {
+		return res + ")", nil
+	}
+	res += " "
 	for i, body := range *stmt.body {
 		if i != 0 {
 			res += " "
@@ -211,6 +294,44 @@ func (s *AstPrinter) visitIfStmt(stmt *If) (interface{}, error) {
 	return s.parenthesize2("if-else", stmt.condition, stmt.thenBranch, stmt.elseBranch)
 }
 
+func (s *AstPrinter) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	res := "(interface " + stmt.name.lexeme
+	for _, method := range *stmt.methods {
+		str, err := s.PrintStatement(method)
+		if err != nil {
+			return "", err
+		}
+		res += " " + str
+	}
+	res += ")"
+	return res, nil
+}
+
+func (s *AstPrinter) visitMatchStmt(stmt *Match) (interface{}, error) {
+	subject, err := s.PrintExpression(stmt.subject)
+	if err != nil {
+		return "", err
+	}
+	res := "(match " + subject
+	for _, matchCase := range *stmt.cases {
+		res += " (case"
+		for _, pattern := range *matchCase.patterns {
+			str, err := s.PrintExpression(pattern)
+			if err != nil {
+				return "", err
+			}
+			res += " " + str
+		}
+		body, err := s.PrintStatement(matchCase.body)
+		if err != nil {
+			return "", err
+		}
+		res += " => " + body + ")"
+	}
+	res += ")"
+	return res, nil
+}
+
 func (s *AstPrinter) visitPrintStmt(stmt *Print) (interface{}, error) {
 	return s.parenthesize("print", stmt.expression)
 }
@@ -222,13 +343,38 @@ func (s *AstPrinter) visitReturnStmt(stmt *Return) (interface{}, error) {
 	return s.parenthesize("return", stmt.value)
 }
 
+func (s *AstPrinter) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	res := "(trait " + stmt.name.lexeme
+	for _, method := range *stmt.methods {
+		str, err := s.PrintStatement(method)
+		if err != nil {
+			return "", err
+		}
+		res += " " + str
+	}
+	res += ")"
+	return res, nil
+}
+
 func (s *AstPrinter) visitVarStmt(stmt *Var) (interface{}, error) {
+	name := stmt.name.lexeme + annotationString(stmt.annotation)
 	if stmt.initializer == nil {
-		return s.parenthesize2("var", stmt.name)
+		return s.parenthesize2("var", name)
 	}
-	return s.parenthesize2("var", stmt.name, "=", stmt.initializer)
+	return s.parenthesize2("var", name, "=", stmt.initializer)
+}
+
+func (s *AstPrinter) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	return s.parenthesize2("var", stmt.pattern, "=", stmt.initializer)
 }
 
 func (s *AstPrinter) visitWhileStmt(stmt *While) (interface{}, error) {
 	return s.parenthesize2("while", stmt.condition, stmt.body)
 }
+
+func annotationString(annotation *Token) string {
+	if annotation == nil {
+		return ""
+	}
+	return ": " + annotation.lexeme
+}
@@ -0,0 +1,54 @@
+package glox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The language server and the debug adapter share the base protocol of LSP:
+// every message is a JSON body preceded by a Content-Length header.
+
+// readMessage reads the body of the next message.
+func readMessage(input *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := input.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, errors.New("Invalid Content-Length header.")
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("Missing Content-Length header.")
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(input, body)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func writeMessage(output io.Writer, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(output, "Content-Length: %v\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,114 @@
+package glox
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Complete is the REPL completer: it offers command names after a leading
+// ":", the members of the value named before a ".", and otherwise keywords
+// and global names.
+func (s *Glox) Complete(prefix string) (int, []string) {
+	start := identifierStart(prefix, len(prefix))
+	word := prefix[start:]
+	var names []string
+	if start == 1 && prefix[0] == ':' {
+		for name := range replCommands() {
+			names = append(names, name)
+		}
+	} else if start > 0 && prefix[start-1] == '.' {
+		names = s.memberNames(prefix[:start-1])
+	} else {
+		for keyword := range *s.tokenMap {
+			names = append(names, keyword)
+		}
+		for name := range s.interpreter.globals.values {
+			names = append(names, name)
+		}
+	}
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return start, candidates
+}
+
+// identifierStart returns the offset where the identifier ending at end starts.
+func identifierStart(source string, end int) int {
+	for end > 0 {
+		ch, size := utf8.DecodeLastRuneInString(source[:end])
+		if !isIdentifierPart(ch) {
+			break
+		}
+		end -= size
+	}
+	return end
+}
+
+// memberNames evaluates the chain of names "a.b.c" ending the source by
+// looking up globals and fields, without calling anything, and returns the
+// names that may follow it.
+func (s *Glox) memberNames(source string) []string {
+	var path []string
+	end := len(source)
+	for {
+		start := identifierStart(source, end)
+		if start == end {
+			return nil
+		}
+		path = append([]string{source[start:end]}, path...)
+		if start == 0 || source[start-1] != '.' {
+			break
+		}
+		end = start - 1
+	}
+	value, ok := s.interpreter.globals.values[path[0]]
+	if !ok {
+		return nil
+	}
+	for _, name := range path[1:] {
+		switch t := value.(type) {
+		case *LoxInstance:
+			value, ok = (*t.fields)[name]
+		case *LoxEnum:
+			ok = false
+			for _, member := range t.members {
+				if member.name == name {
+					value, ok = member, true
+				}
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	var names []string
+	switch t := value.(type) {
+	case *LoxInstance:
+		for name := range *t.fields {
+			names = append(names, name)
+		}
+		for class := t.class; class != nil; class = class.superclass {
+			for name := range *class.methods {
+				names = append(names, name)
+			}
+		}
+	case *LoxEnum:
+		for _, member := range t.members {
+			names = append(names, member.name)
+		}
+		names = append(names, "values")
+	case *LoxEnumMember:
+		names = append(names, "name", "ordinal")
+	}
+	return names
+}
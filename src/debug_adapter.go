@@ -0,0 +1,491 @@
+package glox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DebugAdapter speaks the Debug Adapter Protocol over a pair of streams,
+// usually stdin and stdout, to debug a single program with a single thread.
+// The output of the program is sent to the client as output events.
+type DebugAdapter struct {
+	input  *bufio.Reader
+	output io.Writer
+
+	// mu guards the writes, which also come from the goroutine running the program.
+	mu  sync.Mutex
+	seq int
+
+	program  string
+	debugger *debugger
+	finished chan struct{}
+
+	// breakpointIDs counts the breakpoints set during the session, so their ids never collide.
+	breakpointIDs int
+
+	// handles maps variable references to the values they inspect. They are
+	// only valid while the program is paused.
+	handles []interface{}
+
+	// afterResponse runs once the response to the current request is sent.
+	afterResponse func()
+}
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapBreakpoint struct {
+	ID       int    `json:"id"`
+	Verified bool   `json:"verified"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+type dapStackFrame struct {
+	ID     int        `json:"id"`
+	Name   string     `json:"name"`
+	Source *dapSource `json:"source"`
+	Line   int        `json:"line"`
+	Column int        `json:"column"`
+}
+
+type dapScope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+// debugOutput sends what the program prints as output events.
+type debugOutput struct {
+	adapter *DebugAdapter
+}
+
+func (s *debugOutput) Write(p []byte) (int, error) {
+	err := s.adapter.event("output", map[string]interface{}{"category": "stdout", "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func NewDebugAdapter(input io.Reader, output io.Writer) *DebugAdapter {
+	return &DebugAdapter{
+		input:  bufio.NewReader(input),
+		output: output,
+	}
+}
+
+// Serve answers the requests of the client until it disconnects or closes the input.
+// A request that isn't valid JSON gets a failed response; only a broken frame ends the loop.
+func (s *DebugAdapter) Serve() error {
+	for {
+		body, err := readMessage(s.input)
+		if err == io.EOF {
+			s.stop()
+			return nil
+		}
+		if err != nil {
+			s.stop()
+			return err
+		}
+		request := &dapRequest{}
+		err = json.Unmarshal(body, request)
+		if err != nil {
+			// The request can't be told, so the response doesn't name it.
+			err = s.send(map[string]interface{}{
+				"type":        "response",
+				"request_seq": 0,
+				"command":     "",
+				"success":     false,
+				"message":     err.Error(),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := s.handle(request)
+		response := map[string]interface{}{
+			"type":        "response",
+			"request_seq": request.Seq,
+			"command":     request.Command,
+			"success":     err == nil,
+		}
+		if err != nil {
+			response["message"] = err.Error()
+		} else if result != nil {
+			response["body"] = result
+		}
+		err = s.send(response)
+		if err != nil {
+			return err
+		}
+		if s.afterResponse != nil {
+			s.afterResponse()
+			s.afterResponse = nil
+		}
+		if request.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+func (s *DebugAdapter) send(message map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	message["seq"] = s.seq
+	return writeMessage(s.output, message)
+}
+
+func (s *DebugAdapter) event(name string, body interface{}) error {
+	message := map[string]interface{}{"type": "event", "event": name}
+	if body != nil {
+		message["body"] = body
+	}
+	return s.send(message)
+}
+
+func (s *DebugAdapter) handle(request *dapRequest) (interface{}, error) {
+	switch request.Command {
+	case "initialize":
+		s.afterResponse = func() {
+			_ = s.event("initialized", nil)
+		}
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsConditionalBreakpoints":   true,
+			"supportsFunctionBreakpoints":      true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		return nil, s.launch(request.Arguments)
+	case "setBreakpoints":
+		return s.setBreakpoints(request.Arguments)
+	case "setFunctionBreakpoints":
+		return s.setFunctionBreakpoints(request.Arguments)
+	case "setExceptionBreakpoints":
+		return nil, nil
+	case "configurationDone":
+		return nil, s.configurationDone()
+	case "threads":
+		return map[string]interface{}{
+			"threads": []interface{}{map[string]interface{}{"id": 1, "name": "main"}},
+		}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		return s.scopes(request.Arguments)
+	case "variables":
+		return s.variables(request.Arguments)
+	case "evaluate":
+		return s.evaluate(request.Arguments)
+	case "continue":
+		return map[string]interface{}{"allThreadsContinued": true}, s.resume(commandContinue)
+	case "next":
+		return nil, s.resume(commandStepOver)
+	case "stepIn":
+		return nil, s.resume(commandStepIn)
+	case "stepOut":
+		return nil, s.resume(commandStepOut)
+	case "pause":
+		if s.debugger == nil {
+			return nil, errors.New("No program is running.")
+		}
+		s.debugger.pause()
+		return nil, nil
+	case "terminate", "disconnect":
+		s.stop()
+		return nil, nil
+	}
+	return nil, errors.New("Unknown command '" + request.Command + "'.")
+}
+
+// =====
+
+func (s *DebugAdapter) launch(arguments json.RawMessage) error {
+	var a struct {
+		Program     string `json:"program"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return err
+	}
+	source, err := os.ReadFile(a.Program)
+	if err != nil {
+		return err
+	}
+	debugger, warnings, err := newDebugger(string(source), a.StopOnEntry, s.stopped)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		_ = s.event("output", map[string]interface{}{"category": "stderr", "output": warning.String() + "\n"})
+	}
+	debugger.interpreter.output = &debugOutput{adapter: s}
+	s.program = a.Program
+	s.debugger = debugger
+	return nil
+}
+
+// configurationDone starts the program, once the client has set the breakpoints.
+func (s *DebugAdapter) configurationDone() error {
+	if s.debugger == nil {
+		return errors.New("No program is launched.")
+	}
+	s.finished = make(chan struct{})
+	s.afterResponse = func() {
+		go func() {
+			defer close(s.finished)
+			exitCode := 0
+			err := s.debugger.run()
+			if err != nil {
+				exitCode = 1
+				_ = s.event("output", map[string]interface{}{"category": "stderr", "output": err.Error() + "\n"})
+			}
+			_ = s.event("exited", map[string]interface{}{"exitCode": exitCode})
+			_ = s.event("terminated", nil)
+		}()
+	}
+	return nil
+}
+
+// stopped is called by the debugger on the goroutine running the program.
+func (s *DebugAdapter) stopped(event *stopEvent) {
+	body := map[string]interface{}{
+		"reason":            event.reason,
+		"threadId":          1,
+		"allThreadsStopped": true,
+	}
+	if event.breakpoint != nil {
+		body["hitBreakpointIds"] = []int{event.breakpoint.id}
+	}
+	_ = s.event("stopped", body)
+}
+
+func (s *DebugAdapter) resume(command debugCommand) error {
+	if s.debugger == nil || !s.debugger.isPaused() {
+		return errors.New("The program isn't paused.")
+	}
+	s.handles = nil
+	s.afterResponse = func() {
+		s.debugger.resume(command)
+	}
+	return nil
+}
+
+// stop terminates the program and waits for it to end.
+func (s *DebugAdapter) stop() {
+	if s.debugger == nil || s.finished == nil {
+		return
+	}
+	s.debugger.terminate()
+	<-s.finished
+}
+
+func (s *DebugAdapter) setBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	var a struct {
+		Source      dapSource `json:"source"`
+		Breakpoints []struct {
+			Line      int    `json:"line"`
+			Condition string `json:"condition"`
+		} `json:"breakpoints"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return nil, err
+	}
+	if s.debugger == nil {
+		return nil, errors.New("No program is launched.")
+	}
+	// A breakpoint that can't be set is reported unverified, with the reason.
+	inProgram := sameFile(a.Source.Path, s.program)
+	breakpoints := []*dapBreakpoint{}
+	var created []*breakpoint
+	for _, b := range a.Breakpoints {
+		s.breakpointIDs++
+		result := &dapBreakpoint{ID: s.breakpointIDs, Line: b.Line}
+		breakpoints = append(breakpoints, result)
+		if !inProgram {
+			result.Message = "Not in the program being debugged."
+			continue
+		}
+		lineBreakpoint, err := newBreakpoint(s.breakpointIDs, b.Line, b.Condition)
+		if err != nil {
+			result.Message = err.Error()
+			continue
+		}
+		result.Verified = true
+		created = append(created, lineBreakpoint)
+	}
+	if inProgram {
+		s.debugger.setBreakpoints(created)
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func sameFile(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func (s *DebugAdapter) setFunctionBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	var a struct {
+		Breakpoints []struct {
+			Name string `json:"name"`
+		} `json:"breakpoints"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return nil, err
+	}
+	if s.debugger == nil {
+		return nil, errors.New("No program is launched.")
+	}
+	var names []string
+	breakpoints := []*dapBreakpoint{}
+	for i, b := range a.Breakpoints {
+		names = append(names, b.Name)
+		breakpoints = append(breakpoints, &dapBreakpoint{ID: i + 1, Verified: true})
+	}
+	s.debugger.setFunctionBreakpoints(names)
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// =====
+
+// frame returns a frame of the paused program, frame ids counting from the innermost one.
+func (s *DebugAdapter) frame(id int) (*frame, error) {
+	if s.debugger == nil || !s.debugger.isPaused() {
+		return nil, errors.New("The program isn't paused.")
+	}
+	frames := s.debugger.frames
+	if id < 0 || id >= len(frames) {
+		return nil, errors.New("Unknown frame.")
+	}
+	return frames[len(frames)-1-id], nil
+}
+
+func (s *DebugAdapter) stackTrace() (interface{}, error) {
+	if s.debugger == nil || !s.debugger.isPaused() {
+		return nil, errors.New("The program isn't paused.")
+	}
+	source := &dapSource{Name: filepath.Base(s.program), Path: s.program}
+	frames := []*dapStackFrame{}
+	for i := len(s.debugger.frames) - 1; i >= 0; i-- {
+		f := s.debugger.frames[i]
+		frames = append(frames, &dapStackFrame{
+			ID:     len(frames),
+			Name:   f.name,
+			Source: source,
+			Line:   f.line,
+			Column: 1,
+		})
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+// scopes lists the environment chain of a frame.
+func (s *DebugAdapter) scopes(arguments json.RawMessage) (interface{}, error) {
+	var a struct {
+		FrameID int `json:"frameId"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []*dapScope{}
+	environments := s.debugger.environments(f)
+	for i, environment := range environments {
+		name := "Enclosing"
+		switch {
+		case i == len(environments)-1:
+			name = "Globals"
+		case i == 0:
+			name = "Locals"
+		}
+		scopes = append(scopes, &dapScope{Name: name, VariablesReference: s.reference(environment)})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *DebugAdapter) variables(arguments json.RawMessage) (interface{}, error) {
+	var a struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return nil, err
+	}
+	if a.VariablesReference < 1 || a.VariablesReference > len(s.handles) {
+		return nil, errors.New("Unknown variables reference.")
+	}
+	variables := []*dapVariable{}
+	for _, v := range s.debugger.variables(s.handles[a.VariablesReference-1]) {
+		variables = append(variables, &dapVariable{
+			Name:               v.name,
+			Value:              s.debugger.stringify(v.value),
+			VariablesReference: s.reference(v.value),
+		})
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+func (s *DebugAdapter) evaluate(arguments json.RawMessage) (interface{}, error) {
+	var a struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+	}
+	err := json.Unmarshal(arguments, &a)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.debugger.evaluateSource(a.Expression, f.environment)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"result":             s.debugger.stringify(value),
+		"variablesReference": s.reference(value),
+	}, nil
+}
+
+// reference returns the variables reference of a value, or 0 for the values
+// that can't be inspected.
+func (s *DebugAdapter) reference(value interface{}) int {
+	if !hasVariables(value) {
+		return 0
+	}
+	s.handles = append(s.handles, value)
+	return len(s.handles)
+}
@@ -0,0 +1,371 @@
+package glox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DebugCLI is a command-line debugger with a gdb-like prompt. Breakpoints
+// and watches can be set before the program runs; "run" starts it again
+// from the beginning once it has ended.
+type DebugCLI struct {
+	path   string
+	input  *bufio.Reader
+	output io.Writer
+
+	breakpoints []*cliBreakpoint
+	watches     []string
+
+	// debugger is the running program, or nil.
+	debugger *debugger
+	lines    []string
+	stops    chan *stopEvent
+	finished chan error
+
+	// lastFrame is the frame of the previous stop, to tell when a step leaves it.
+	lastFrame *frame
+	values    int
+}
+
+// cliBreakpoint is a breakpoint on a line, with an optional condition, or on a function.
+type cliBreakpoint struct {
+	line      int
+	condition string
+	function  string
+}
+
+type debugCLICommand struct {
+	usage       string
+	description string
+	run         func(s *DebugCLI, argument string) error
+}
+
+func debugCLICommands() map[string]*debugCLICommand {
+	return map[string]*debugCLICommand{
+		"break":     {"break LINE [if EXPR] | break NAME", "Stop at a line, or when a function is called", (*DebugCLI).breakCommand},
+		"run":       {"run", "Run the program from the beginning", (*DebugCLI).runCommand},
+		"next":      {"next", "Run to the next line, stepping over calls", (*DebugCLI).nextCommand},
+		"step":      {"step", "Run to the next line, stepping into calls", (*DebugCLI).stepCommand},
+		"finish":    {"finish", "Run until the current function returns", (*DebugCLI).finishCommand},
+		"continue":  {"continue", "Run until a breakpoint or a watch stops the program", (*DebugCLI).continueCommand},
+		"print":     {"print EXPR", "Evaluate an expression in the current frame", (*DebugCLI).printCommand},
+		"backtrace": {"backtrace", "Show the call stack", (*DebugCLI).backtraceCommand},
+		"watch":     {"watch NAME", "Stop when the value of a variable changes", (*DebugCLI).watchCommand},
+		"help":      {"help", "List the commands", (*DebugCLI).helpCommand},
+	}
+}
+
+// debugCLIAliases are the gdb abbreviations of the commands.
+var debugCLIAliases = map[string]string{
+	"b":   "break",
+	"r":   "run",
+	"n":   "next",
+	"s":   "step",
+	"fin": "finish",
+	"c":   "continue",
+	"p":   "print",
+	"bt":  "backtrace",
+	"h":   "help",
+}
+
+func NewDebugCLI(path string, input io.Reader, output io.Writer) *DebugCLI {
+	return &DebugCLI{
+		path:   path,
+		input:  bufio.NewReader(input),
+		output: output,
+	}
+}
+
+// Run reads commands until "quit" or the end of the input. An empty line
+// repeats the previous command.
+func (s *DebugCLI) Run() int {
+	previous := ""
+	for {
+		_, _ = fmt.Fprint(s.output, "(glox) ")
+		line, err := s.input.ReadString('\n')
+		if err != nil && line == "" {
+			_, _ = fmt.Fprintln(s.output)
+			s.kill()
+			return 0
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = previous
+		}
+		if line == "" {
+			continue
+		}
+		previous = line
+		name, argument, _ := strings.Cut(line, " ")
+		argument = strings.TrimSpace(argument)
+		if name == "quit" || name == "q" {
+			s.kill()
+			return 0
+		}
+		if alias, ok := debugCLIAliases[name]; ok {
+			name = alias
+		}
+		command, ok := debugCLICommands()[name]
+		if !ok {
+			_, _ = fmt.Fprintln(s.output, "[Debug]", "Unknown command '"+name+"', see 'help'.")
+			continue
+		}
+		err = command.run(s, argument)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.output, "[Debug]", err.Error())
+		}
+	}
+}
+
+func (s *DebugCLI) helpCommand(_ string) error {
+	commands := debugCLICommands()
+	for _, name := range []string{"break", "run", "next", "step", "finish", "continue", "print", "backtrace", "watch", "help"} {
+		_, _ = fmt.Fprintf(s.output, "%-34v %v\n", commands[name].usage, commands[name].description)
+	}
+	_, _ = fmt.Fprintf(s.output, "%-34v %v\n", "quit", "Stop the program and leave")
+	return nil
+}
+
+func (s *DebugCLI) breakCommand(argument string) error {
+	if argument == "" {
+		return fmt.Errorf("Expect a line or a function name after 'break'.")
+	}
+	b := &cliBreakpoint{}
+	location, condition, hasCondition := strings.Cut(argument, " if ")
+	location = strings.TrimSpace(location)
+	if line, err := strconv.Atoi(location); err == nil {
+		b.line = line
+		if hasCondition {
+			_, err = parseDebugExpression(condition)
+			if err != nil {
+				return err
+			}
+			b.condition = strings.TrimSpace(condition)
+		}
+	} else if hasCondition {
+		return fmt.Errorf("Only line breakpoints can have a condition.")
+	} else {
+		b.function = location
+	}
+	s.breakpoints = append(s.breakpoints, b)
+	if b.function != "" {
+		_, _ = fmt.Fprintf(s.output, "Breakpoint %v at function %v\n", len(s.breakpoints), b.function)
+	} else {
+		_, _ = fmt.Fprintf(s.output, "Breakpoint %v at line %v\n", len(s.breakpoints), b.line)
+	}
+	if s.debugger != nil {
+		return s.applyBreakpoints()
+	}
+	return nil
+}
+
+func (s *DebugCLI) applyBreakpoints() error {
+	var breakpoints []*breakpoint
+	var functions []string
+	for i, b := range s.breakpoints {
+		if b.function != "" {
+			functions = append(functions, b.function)
+			continue
+		}
+		lineBreakpoint, err := newBreakpoint(i+1, b.line, b.condition)
+		if err != nil {
+			return err
+		}
+		breakpoints = append(breakpoints, lineBreakpoint)
+	}
+	s.debugger.setFunctionBreakpoints(functions)
+	s.debugger.setBreakpoints(breakpoints)
+	return nil
+}
+
+func (s *DebugCLI) watchCommand(argument string) error {
+	if !isIdentifierName(argument) {
+		return fmt.Errorf("Expect a variable name after 'watch'.")
+	}
+	if s.debugger != nil {
+		_, err := s.debugger.addWatch(argument, s.debugger.frames[len(s.debugger.frames)-1].environment)
+		if err != nil {
+			return err
+		}
+	}
+	s.watches = append(s.watches, argument)
+	_, _ = fmt.Fprintf(s.output, "Watchpoint %v: %v\n", len(s.watches), argument)
+	return nil
+}
+
+func isIdentifierName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range name {
+		if i == 0 && !isIdentifierStart(ch) || !isIdentifierPart(ch) {
+			return false
+		}
+	}
+	_, keyword := (*NewTokenMap())[name]
+	return !keyword
+}
+
+// runCommand starts the program, after stopping the one that is running.
+// The watches are set again on the globals.
+func (s *DebugCLI) runCommand(_ string) error {
+	s.kill()
+	source, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	stops := make(chan *stopEvent)
+	debugger, warnings, err := newDebugger(string(source), false, func(event *stopEvent) {
+		stops <- event
+	})
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintln(s.output, "[Resolver]", warning.String())
+	}
+	debugger.interpreter.output = s.output
+	s.debugger = debugger
+	s.lines = strings.Split(string(source), "\n")
+	s.stops = stops
+	s.finished = make(chan error, 1)
+	s.lastFrame = nil
+	err = s.applyBreakpoints()
+	if err != nil {
+		s.debugger = nil
+		return err
+	}
+	for _, name := range s.watches {
+		_, _ = s.debugger.addWatch(name, nil)
+	}
+	go func() {
+		s.finished <- debugger.run()
+	}()
+	s.wait()
+	return nil
+}
+
+func (s *DebugCLI) nextCommand(_ string) error {
+	return s.resume(commandStepOver)
+}
+
+func (s *DebugCLI) stepCommand(_ string) error {
+	return s.resume(commandStepIn)
+}
+
+func (s *DebugCLI) finishCommand(_ string) error {
+	if s.debugger != nil && len(s.debugger.frames) == 1 {
+		return fmt.Errorf("'finish' is meaningless in the outermost frame.")
+	}
+	return s.resume(commandStepOut)
+}
+
+func (s *DebugCLI) continueCommand(_ string) error {
+	return s.resume(commandContinue)
+}
+
+func (s *DebugCLI) resume(command debugCommand) error {
+	if s.debugger == nil {
+		return fmt.Errorf("The program is not being run.")
+	}
+	s.debugger.resume(command)
+	s.wait()
+	return nil
+}
+
+// wait waits for the program to stop or to end, and tells where it stopped.
+func (s *DebugCLI) wait() {
+	select {
+	case event := <-s.stops:
+		current := s.debugger.frames[len(s.debugger.frames)-1]
+		switch event.reason {
+		case "breakpoint":
+			_, _ = fmt.Fprintf(s.output, "Breakpoint %v, %v at line %v\n", s.breakpointNumber(event.line, ""), current.name, event.line)
+		case "function breakpoint":
+			_, _ = fmt.Fprintf(s.output, "Breakpoint %v, %v at line %v\n", s.breakpointNumber(0, current.name), current.name, event.line)
+		case "watch":
+			_, _ = fmt.Fprintf(s.output, "Watchpoint %v: %v\nOld value = %v\nNew value = %v\n",
+				s.watchNumber(event.watch.name), event.watch.name,
+				s.debugger.stringify(event.oldValue), s.debugger.stringify(event.watch.value))
+			_, _ = fmt.Fprintf(s.output, "%v at line %v\n", current.name, event.line)
+		default:
+			if current != s.lastFrame {
+				_, _ = fmt.Fprintf(s.output, "%v at line %v\n", current.name, event.line)
+			}
+		}
+		s.lastFrame = current
+		if event.line <= len(s.lines) {
+			_, _ = fmt.Fprintf(s.output, "%v\t%v\n", event.line, strings.TrimRight(s.lines[event.line-1], "\r"))
+		}
+	case err := <-s.finished:
+		if err != nil {
+			_, _ = fmt.Fprintln(s.output, "[Interpreter]", err.Error())
+			_, _ = fmt.Fprintln(s.output, "[Program exited with code 1]")
+		} else {
+			_, _ = fmt.Fprintln(s.output, "[Program exited normally]")
+		}
+		s.debugger = nil
+	}
+}
+
+func (s *DebugCLI) breakpointNumber(line int, function string) int {
+	for i, b := range s.breakpoints {
+		if function != "" && b.function == function || function == "" && b.function == "" && b.line == line {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (s *DebugCLI) watchNumber(name string) int {
+	for i, watched := range s.watches {
+		if watched == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// kill terminates the running program and waits for it to end.
+func (s *DebugCLI) kill() {
+	if s.debugger == nil {
+		return
+	}
+	s.debugger.terminate()
+	<-s.finished
+	s.debugger = nil
+}
+
+// =====
+
+func (s *DebugCLI) printCommand(argument string) error {
+	if s.debugger == nil {
+		return fmt.Errorf("The program is not being run.")
+	}
+	if argument == "" {
+		return fmt.Errorf("Expect an expression after 'print'.")
+	}
+	current := s.debugger.frames[len(s.debugger.frames)-1]
+	value, err := s.debugger.evaluateSource(argument, current.environment)
+	if err != nil {
+		return err
+	}
+	s.values++
+	_, _ = fmt.Fprintf(s.output, "$%v = %v\n", s.values, s.debugger.stringify(value))
+	return nil
+}
+
+func (s *DebugCLI) backtraceCommand(_ string) error {
+	if s.debugger == nil {
+		return fmt.Errorf("The program is not being run.")
+	}
+	frames := s.debugger.frames
+	for i := len(frames) - 1; i >= 0; i-- {
+		_, _ = fmt.Fprintf(s.output, "#%v  %v at line %v\n", len(frames)-1-i, frames[i].name, frames[i].line)
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package glox
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// debugger pauses an interpreter at breakpoints and between steps. The
+// program runs on its own goroutine: when it stops, the debugger calls onStop
+// and waits for a command, and meanwhile the frames of the paused program can
+// be inspected from the goroutine that drives it.
+type debugger struct {
+	interpreter *Interpreter
+	info        *sourceInfo
+	statements  []Stmt
+	onStop      func(event *stopEvent)
+	commands    chan debugCommand
+
+	frames []*frame
+
+	// watches are only changed while the program is paused or before it runs.
+	watches []*watch
+
+	// step is the command that resumed the program, and stepDepth and
+	// stepLine where it was paused then.
+	step      debugCommand
+	stepDepth int
+	stepLine  int
+
+	// evaluating turns the debugger off while it evaluates expressions itself.
+	evaluating bool
+
+	mu                  sync.Mutex
+	breakpoints         map[int]*breakpoint
+	functionBreakpoints map[string]bool
+	paused              bool
+	pauseRequested      bool
+	terminated          bool
+}
+
+type debugCommand int
+
+const (
+	commandContinue debugCommand = iota
+	commandStepIn
+	commandStepOver
+	commandStepOut
+	commandTerminate
+)
+
+// frame is a function call, or the top-level code of the program. Its
+// environment and line are those of the statement it is executing.
+type frame struct {
+	name        string
+	environment *Environment
+	line        int
+}
+
+// breakpoint stops the program before the first statement on its line, when
+// its condition, if any, is truthy.
+type breakpoint struct {
+	id        int
+	line      int
+	condition Expr
+}
+
+// watch stops the program when the value bound to a name in an environment changes.
+type watch struct {
+	name        string
+	environment *Environment
+	value       interface{}
+	defined     bool
+}
+
+type stopEvent struct {
+	reason     string
+	line       int
+	breakpoint *breakpoint
+	watch      *watch
+	oldValue   interface{}
+}
+
+// variable is a named value shown when inspecting environments and values.
+type variable struct {
+	name  string
+	value interface{}
+}
+
+var errTerminated = errors.New("Terminated by the debugger.")
+
+// newDebugger scans, parses and resolves a program to debug. With
+// stopOnEntry, the program stops before its first statement.
+func newDebugger(source string, stopOnEntry bool, onStop func(event *stopEvent)) (*debugger, []*ResolverWarning, error) {
+	tokens, err := NewScanner(NewTokenMap(), source).ScanTokens()
+	if err != nil {
+		return nil, nil, err
+	}
+	parser := NewParser(&tokens)
+	statements, err := parser.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	interpreter := NewInterpreter()
+	resolver := NewResolver(interpreter)
+	err = resolver.resolveStatements(&statements)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &debugger{
+		interpreter:         interpreter,
+		info:                parser.info,
+		statements:          statements,
+		onStop:              onStop,
+		commands:            make(chan debugCommand),
+		step:                commandContinue,
+		breakpoints:         map[int]*breakpoint{},
+		functionBreakpoints: map[string]bool{},
+	}
+	if stopOnEntry {
+		s.step = commandStepIn
+	}
+	interpreter.tracer = s
+	return s, resolver.Warnings(), nil
+}
+
+// run runs the program until it ends or is terminated.
+func (s *debugger) run() error {
+	s.frames = []*frame{{name: "<script>", environment: s.interpreter.globals}}
+	err := s.interpreter.Interpret(&s.statements, nil)
+	if err == errTerminated {
+		return nil
+	}
+	return err
+}
+
+// resume sends a command to the paused program.
+func (s *debugger) resume(command debugCommand) {
+	s.commands <- command
+}
+
+// pause stops the running program before its next statement.
+func (s *debugger) pause() {
+	s.mu.Lock()
+	s.pauseRequested = true
+	s.mu.Unlock()
+}
+
+// terminate stops the program, whether it is paused or running.
+func (s *debugger) terminate() {
+	s.mu.Lock()
+	s.terminated = true
+	paused := s.paused
+	s.mu.Unlock()
+	if paused {
+		s.resume(commandTerminate)
+	}
+}
+
+func (s *debugger) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// newBreakpoint makes a breakpoint on a line, parsing its condition if there is one.
+func newBreakpoint(id int, line int, condition string) (*breakpoint, error) {
+	b := &breakpoint{id: id, line: line}
+	if condition != "" {
+		expression, err := parseDebugExpression(condition)
+		if err != nil {
+			return nil, err
+		}
+		b.condition = expression
+	}
+	return b, nil
+}
+
+// setBreakpoints replaces the line breakpoints.
+func (s *debugger) setBreakpoints(list []*breakpoint) {
+	breakpoints := map[int]*breakpoint{}
+	for _, b := range list {
+		breakpoints[b.line] = b
+	}
+	s.mu.Lock()
+	s.breakpoints = breakpoints
+	s.mu.Unlock()
+}
+
+func (s *debugger) setFunctionBreakpoints(names []string) {
+	functionBreakpoints := map[string]bool{}
+	for _, name := range names {
+		functionBreakpoints[name] = true
+	}
+	s.mu.Lock()
+	s.functionBreakpoints = functionBreakpoints
+	s.mu.Unlock()
+}
+
+// addWatch watches the innermost binding of the name visible from the
+// environment, or a global when there is none yet.
+func (s *debugger) addWatch(name string, environment *Environment) (*watch, error) {
+	if environment == nil {
+		environment = s.interpreter.globals
+	}
+	for e := environment; e != nil; e = e.enclosing {
+		if value, ok := e.values[name]; ok {
+			w := &watch{name: name, environment: e, value: value, defined: true}
+			s.watches = append(s.watches, w)
+			return w, nil
+		}
+	}
+	if environment != s.interpreter.globals {
+		return nil, errors.New("No variable '" + name + "' in the current scope.")
+	}
+	w := &watch{name: name, environment: environment}
+	s.watches = append(s.watches, w)
+	return w, nil
+}
+
+// =====
+
+func (s *debugger) enterFunction(function *LoxFunction, environment *Environment) {
+	if s.evaluating {
+		return
+	}
+	name := function.declaration.name.lexeme
+	if instance, ok := function.closure.values["this"].(*LoxInstance); ok {
+		name = instance.class.name + "." + name
+	}
+	s.frames = append(s.frames, &frame{name: name, environment: environment})
+}
+
+func (s *debugger) leaveFunction() {
+	if s.evaluating {
+		return
+	}
+	s.frames = s.frames[:len(s.frames)-1]
+}
+
+// beforeStatement stops the program before a statement when it should,
+// and waits for the command resuming it.
+func (s *debugger) beforeStatement(stmt Stmt) error {
+	if s.evaluating {
+		return nil
+	}
+	line := s.info.startLine(stmt)
+	if line == 0 {
+		return nil
+	}
+	current := s.frames[len(s.frames)-1]
+	previous := current.line
+	current.environment = s.interpreter.environment
+	current.line = line
+
+	event, err := s.stopEvent(current, previous)
+	if event == nil || err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.terminated {
+		s.mu.Unlock()
+		return errTerminated
+	}
+	s.paused = true
+	s.mu.Unlock()
+	s.onStop(event)
+	command := <-s.commands
+	s.mu.Lock()
+	s.paused = false
+	s.mu.Unlock()
+	if command == commandTerminate {
+		return errTerminated
+	}
+	s.step = command
+	s.stepDepth = len(s.frames)
+	s.stepLine = line
+	return nil
+}
+
+// stopEvent tells why the program stops before a statement on the line of
+// the current frame, or returns nil when it doesn't. A breakpoint only
+// stops the program when it enters the line.
+func (s *debugger) stopEvent(current *frame, previous int) (*stopEvent, error) {
+	s.mu.Lock()
+	terminated := s.terminated
+	pauseRequested := s.pauseRequested
+	s.pauseRequested = false
+	b := s.breakpoints[current.line]
+	functionBreakpoint := previous == 0 && s.functionBreakpoints[current.name]
+	s.mu.Unlock()
+
+	if terminated {
+		return nil, errTerminated
+	}
+	if pauseRequested {
+		return &stopEvent{reason: "pause", line: current.line}, nil
+	}
+	if b != nil && current.line != previous {
+		hit := true
+		if b.condition != nil {
+			value, err := s.evaluate(b.condition, current.environment)
+			hit = err == nil && s.interpreter.isTruthy(value)
+		}
+		if hit {
+			return &stopEvent{reason: "breakpoint", line: current.line, breakpoint: b}, nil
+		}
+	}
+	if functionBreakpoint {
+		return &stopEvent{reason: "function breakpoint", line: current.line}, nil
+	}
+	if event := s.watchEvent(current.line); event != nil {
+		return event, nil
+	}
+
+	depth := len(s.frames)
+	stepped := false
+	switch s.step {
+	case commandStepIn:
+		stepped = depth != s.stepDepth || current.line != s.stepLine
+	case commandStepOver:
+		stepped = depth < s.stepDepth || depth == s.stepDepth && current.line != s.stepLine
+	case commandStepOut:
+		stepped = depth < s.stepDepth
+	}
+	if stepped {
+		reason := "step"
+		if s.stepLine == 0 {
+			reason = "entry"
+		}
+		return &stopEvent{reason: reason, line: current.line}, nil
+	}
+	return nil, nil
+}
+
+// watchEvent updates the values of the watches, and reports the first one
+// that changed since the previous statement. A name being defined isn't a change.
+func (s *debugger) watchEvent(line int) *stopEvent {
+	var event *stopEvent
+	for _, w := range s.watches {
+		value, defined := w.environment.values[w.name]
+		if event == nil && w.defined && defined && !sameValue(w.value, value) {
+			event = &stopEvent{reason: "watch", line: line, watch: w, oldValue: w.value}
+		}
+		w.value = value
+		w.defined = defined
+	}
+	return event
+}
+
+// sameValue compares values without calling "equals" methods.
+func sameValue(a interface{}, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return numbersEqual(a, b)
+	}
+	return a == b
+}
+
+// =====
+
+func parseDebugExpression(source string) (Expr, error) {
+	tokens, err := NewScanner(NewTokenMap(), source).ScanTokens()
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(&tokens).ParseExpression()
+}
+
+// evaluateSource evaluates an expression in the environment of a frame.
+func (s *debugger) evaluateSource(source string, environment *Environment) (interface{}, error) {
+	expr, err := parseDebugExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(expr, environment)
+}
+
+// evaluate resolves the expression against the names defined in the
+// environment, then evaluates it there without stopping.
+func (s *debugger) evaluate(expr Expr, environment *Environment) (interface{}, error) {
+	resolver := NewResolver(s.interpreter)
+	var chain []*Environment
+	for e := environment; e != nil && e != s.interpreter.globals; e = e.enclosing {
+		chain = append([]*Environment{e}, chain...)
+	}
+	for _, e := range chain {
+		resolver.beginScope()
+		for name := range e.values {
+			(*resolver.scopes.peek())[name] = true
+			if e.constants[name] {
+				resolver.scopes.markConstant(name)
+			}
+			if name == "super" {
+				resolver.currentClass = CSubclass
+			} else if name == "this" && resolver.currentClass == CNone {
+				resolver.currentClass = CClass
+			}
+		}
+	}
+	err := resolver.resolveExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	enclosing := s.interpreter.environment
+	s.interpreter.environment = environment
+	s.evaluating = true
+	defer func() {
+		s.interpreter.environment = enclosing
+		s.evaluating = false
+	}()
+	return s.interpreter.evaluate(expr)
+}
+
+// stringify formats a value without stopping in its toString method.
+func (s *debugger) stringify(value interface{}) string {
+	s.evaluating = true
+	defer func() {
+		s.evaluating = false
+	}()
+	return s.interpreter.Stringify(value)
+}
+
+// environments returns the chain of environments of a frame, from the
+// innermost to the globals.
+func (s *debugger) environments(f *frame) []*Environment {
+	var chain []*Environment
+	for e := f.environment; e != nil; e = e.enclosing {
+		chain = append(chain, e)
+	}
+	return chain
+}
+
+// variables lists what a value holds: the bindings of an environment, the
+// fields of an instance, or the elements of a list or a map. Native
+// functions are left out of the globals.
+func (s *debugger) variables(value interface{}) []*variable {
+	var variables []*variable
+	switch t := value.(type) {
+	case *Environment:
+		for name, value := range t.values {
+			if _, ok := value.(LoxCallable); ok && t == s.interpreter.globals {
+				switch value.(type) {
+				case *LoxFunction, *LoxClass:
+				default:
+					continue
+				}
+			}
+			variables = append(variables, &variable{name, value})
+		}
+	case *LoxInstance:
+		for name, value := range *t.fields {
+			variables = append(variables, &variable{name, value})
+		}
+		for key, value := range t.privates {
+			variables = append(variables, &variable{key.name, value})
+		}
+	case *LoxList:
+		for i, element := range t.elements {
+			variables = append(variables, &variable{"[" + strconv.Itoa(i) + "]", element})
+		}
+		return variables
+	case *LoxMap:
+		for i, key := range t.keys {
+			variables = append(variables, &variable{s.stringify(key), t.values[t.slots[i]]})
+		}
+		return variables
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].name < variables[j].name
+	})
+	return variables
+}
+
+// hasVariables reports whether a value can be inspected with variables.
+func hasVariables(value interface{}) bool {
+	switch value.(type) {
+	case *Environment, *LoxInstance, *LoxList, *LoxMap:
+		return true
+	}
+	return false
+}
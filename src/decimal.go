@@ -0,0 +1,205 @@
+package glox
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Decimal is an exact decimal number: unscaled × 10^-scale.
+// Addition, subtraction and multiplication are exact, and division is computed
+// with decimalContext.precision digits after the decimal point. Any result with
+// more digits than that is rounded using decimalContext.rounding.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota
+	RoundHalfUp
+	RoundHalfDown
+	RoundUp
+	RoundDown
+	RoundCeiling
+	RoundFloor
+)
+
+var roundingModes = map[string]RoundingMode{
+	"half_even": RoundHalfEven,
+	"half_up":   RoundHalfUp,
+	"half_down": RoundHalfDown,
+	"up":        RoundUp,
+	"down":      RoundDown,
+	"ceiling":   RoundCeiling,
+	"floor":     RoundFloor,
+}
+
+type decimalContext struct {
+	precision int
+	rounding  RoundingMode
+}
+
+func newDecimalContext() *decimalContext {
+	return &decimalContext{
+		precision: 20,
+		rounding:  RoundHalfEven,
+	}
+}
+
+func NewDecimal(unscaled *big.Int, scale int) *Decimal {
+	return &Decimal{
+		unscaled: unscaled,
+		scale:    scale,
+	}
+}
+
+// parseDecimal parses digits with an optional fractional part, e.g. "19.99".
+func parseDecimal(text string) *Decimal {
+	scale := 0
+	if dot := strings.IndexByte(text, '.'); dot != -1 {
+		scale = len(text) - dot - 1
+		text = text[:dot] + text[dot+1:]
+	}
+	unscaled, _ := new(big.Int).SetString(text, 10)
+	return NewDecimal(unscaled, scale)
+}
+
+func toDecimal(obj interface{}) *Decimal {
+	switch v := obj.(type) {
+	case *Decimal:
+		return v
+	case int64, *big.Int:
+		return NewDecimal(toBigInt(v), 0)
+	}
+	return nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// rescale returns the unscaled value of s at a larger scale.
+func (s *Decimal) rescale(scale int) *big.Int {
+	return new(big.Int).Mul(s.unscaled, pow10(scale-s.scale))
+}
+
+func (s *Decimal) cmp(other *Decimal) int {
+	scale := s.scale
+	if other.scale > scale {
+		scale = other.scale
+	}
+	return s.rescale(scale).Cmp(other.rescale(scale))
+}
+
+func (s *Decimal) neg() *Decimal {
+	return NewDecimal(new(big.Int).Neg(s.unscaled), s.scale)
+}
+
+// round limits the number of digits after the decimal point to context.precision.
+func (s *Decimal) round(context *decimalContext) *Decimal {
+	if s.scale <= context.precision {
+		return s
+	}
+	divisor := pow10(s.scale - context.precision)
+	quotient, remainder := new(big.Int).QuoRem(s.unscaled, divisor, new(big.Int))
+	return NewDecimal(roundQuotient(quotient, remainder, divisor, s.unscaled.Sign(), context.rounding), context.precision)
+}
+
+// stripZeros removes trailing zeros after the decimal point, keeping at least minScale digits.
+func (s *Decimal) stripZeros(minScale int) *Decimal {
+	unscaled, scale := new(big.Int).Set(s.unscaled), s.scale
+	ten, remainder := big.NewInt(10), new(big.Int)
+	for scale > minScale && scale > 0 {
+		quotient, _ := new(big.Int).QuoRem(unscaled, ten, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		unscaled, scale = quotient, scale-1
+	}
+	return NewDecimal(unscaled, scale)
+}
+
+// roundQuotient adjusts a truncated quotient according to the remainder of the division.
+func roundQuotient(quotient *big.Int, remainder *big.Int, divisor *big.Int, sign int, mode RoundingMode) *big.Int {
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+	half := new(big.Int).Abs(remainder)
+	half.Mul(half, big.NewInt(2))
+	half.Sub(half, new(big.Int).Abs(divisor))
+	awayFromZero := false
+	switch mode {
+	case RoundHalfEven:
+		awayFromZero = half.Sign() > 0 || (half.Sign() == 0 && quotient.Bit(0) == 1)
+	case RoundHalfUp:
+		awayFromZero = half.Sign() >= 0
+	case RoundHalfDown:
+		awayFromZero = half.Sign() > 0
+	case RoundUp:
+		awayFromZero = true
+	case RoundDown:
+		awayFromZero = false
+	case RoundCeiling:
+		awayFromZero = sign > 0
+	case RoundFloor:
+		awayFromZero = sign < 0
+	}
+	if awayFromZero {
+		return new(big.Int).Add(quotient, big.NewInt(int64(sign)))
+	}
+	return quotient
+}
+
+func decimalArithmetic(operator *Token, l *Decimal, r *Decimal, context *decimalContext) (*Decimal, error) {
+	scale := l.scale
+	if r.scale > scale {
+		scale = r.scale
+	}
+	var result *Decimal
+	switch operator.tokenType {
+	case TokenPlus:
+		result = NewDecimal(new(big.Int).Add(l.rescale(scale), r.rescale(scale)), scale)
+	case TokenMinus:
+		result = NewDecimal(new(big.Int).Sub(l.rescale(scale), r.rescale(scale)), scale)
+	case TokenStar:
+		result = NewDecimal(new(big.Int).Mul(l.unscaled, r.unscaled), l.scale+r.scale)
+	case TokenSlash:
+		if r.unscaled.Sign() == 0 {
+			return nil, NewRuntimeError(operator, "Division by zero.")
+		}
+		// l / r = (l.unscaled * 10^exponent / r.unscaled) * 10^-precision
+		numerator, denominator := new(big.Int).Set(l.unscaled), new(big.Int).Set(r.unscaled)
+		exponent := context.precision + r.scale - l.scale
+		if exponent >= 0 {
+			numerator.Mul(numerator, pow10(exponent))
+		} else {
+			denominator.Mul(denominator, pow10(-exponent))
+		}
+		quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+		sign := numerator.Sign() * denominator.Sign()
+		quotient = roundQuotient(quotient, remainder, denominator, sign, context.rounding)
+		minScale := l.scale
+		if minScale > context.precision {
+			minScale = context.precision
+		}
+		return NewDecimal(quotient, context.precision).stripZeros(minScale), nil
+	}
+	return result.round(context), nil
+}
+
+func (s *Decimal) String() string {
+	digits := new(big.Int).Abs(s.unscaled).String()
+	sign := ""
+	if s.unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if s.scale <= 0 {
+		return sign + digits + strings.Repeat("0", -s.scale)
+	}
+	if len(digits) <= s.scale {
+		digits = strings.Repeat("0", s.scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-s.scale] + "." + digits[len(digits)-s.scale:]
+}
@@ -5,12 +5,14 @@ import "fmt"
 type Environment struct {
 	enclosing *Environment
 	values    map[string]interface{}
+	constants map[string]bool
 }
 
 func NewEnvironment(enclosing *Environment) *Environment {
 	return &Environment{
 		enclosing: enclosing,
 		values:    make(map[string]interface{}),
+		constants: make(map[string]bool),
 	}
 }
 
@@ -19,6 +21,18 @@ func (s *Environment) define(name string, value interface{}) error {
 	return nil
 }
 
+// declare defines a name coming from a declaration in the source code.
+// Unlike define, it refuses to redefine a constant (which is only possible at global scope).
+func (s *Environment) declare(name *Token, value interface{}, constant bool) error {
+	if s.constants[name.lexeme] {
+		return NewRuntimeError(name, fmt.Sprintf("Can't redefine constant '%v'.", name.lexeme))
+	}
+	if constant {
+		s.constants[name.lexeme] = true
+	}
+	return s.define(name.lexeme, value)
+}
+
 func (s *Environment) get(name *Token) (interface{}, error) {
 	if obj, ok := s.values[name.lexeme]; ok {
 		return obj, nil
@@ -46,6 +60,9 @@ func (s *Environment) ancestor(distance int) *Environment {
 
 func (s *Environment) assign(name *Token, value interface{}) error {
 	if _, ok := s.values[name.lexeme]; ok {
+		if s.constants[name.lexeme] {
+			return NewRuntimeError(name, fmt.Sprintf("Can't assign to constant '%v'.", name.lexeme))
+		}
 		s.values[name.lexeme] = value
 		return nil
 	} else if s.enclosing != nil {
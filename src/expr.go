@@ -8,13 +8,18 @@ type Expr interface {
 
 type exprVisitor interface {
 	visitAssignExpr(expr *Assign) (interface{}, error)
+	visitAssignPatternExpr(expr *AssignPattern) (interface{}, error)
 	visitBinaryExpr(expr *Binary) (interface{}, error)
 	visitCallExpr(expr *Call) (interface{}, error)
 	visitGetExpr(expr *Get) (interface{}, error)
 	visitGroupingExpr(expr *Grouping) (interface{}, error)
+	visitListLiteralExpr(expr *ListLiteral) (interface{}, error)
 	visitLiteralExpr(expr *Literal) (interface{}, error)
 	visitLogicalExpr(expr *Logical) (interface{}, error)
+	visitMapLiteralExpr(expr *MapLiteral) (interface{}, error)
+	visitObjectPatternExpr(expr *ObjectPattern) (interface{}, error)
 	visitSetExpr(expr *Set) (interface{}, error)
+	visitSpreadExpr(expr *Spread) (interface{}, error)
 	visitSuperExpr(expr *Super) (interface{}, error)
 	visitThisExpr(expr *This) (interface{}, error)
 	visitUnaryExpr(expr *Unary) (interface{}, error)
@@ -37,6 +42,24 @@ func (expr *Assign) accept(visitor exprVisitor) (interface{}, error) {
 	return visitor.visitAssignExpr(expr)
 }
 
+type AssignPattern struct {
+	pattern Expr
+	equals  *Token
+	value   Expr
+}
+
+func NewAssignPattern(pattern Expr, equals *Token, value Expr) *AssignPattern {
+	expr := new(AssignPattern)
+	expr.pattern = pattern
+	expr.equals = equals
+	expr.value = value
+	return expr
+}
+
+func (expr *AssignPattern) accept(visitor exprVisitor) (interface{}, error) {
+	return visitor.visitAssignPatternExpr(expr)
+}
+
 type Binary struct {
 	left     Expr
 	operator *Token
@@ -103,6 +126,22 @@ func (expr *Grouping) accept(visitor exprVisitor) (interface{}, error) {
 	return visitor.visitGroupingExpr(expr)
 }
 
+type ListLiteral struct {
+	bracket  *Token
+	elements *[]Expr
+}
+
+func NewListLiteral(bracket *Token, elements *[]Expr) *ListLiteral {
+	expr := new(ListLiteral)
+	expr.bracket = bracket
+	expr.elements = elements
+	return expr
+}
+
+func (expr *ListLiteral) accept(visitor exprVisitor) (interface{}, error) {
+	return visitor.visitListLiteralExpr(expr)
+}
+
 type Literal struct {
 	value interface{}
 }
@@ -135,6 +174,42 @@ func (expr *Logical) accept(visitor exprVisitor) (interface{}, error) {
 	return visitor.visitLogicalExpr(expr)
 }
 
+type MapLiteral struct {
+	brace  *Token
+	keys   *[]Expr
+	values *[]Expr
+}
+
+func NewMapLiteral(brace *Token, keys *[]Expr, values *[]Expr) *MapLiteral {
+	expr := new(MapLiteral)
+	expr.brace = brace
+	expr.keys = keys
+	expr.values = values
+	return expr
+}
+
+func (expr *MapLiteral) accept(visitor exprVisitor) (interface{}, error) {
+	return visitor.visitMapLiteralExpr(expr)
+}
+
+type ObjectPattern struct {
+	brace  *Token
+	keys   *[]*Token
+	values *[]Expr
+}
+
+func NewObjectPattern(brace *Token, keys *[]*Token, values *[]Expr) *ObjectPattern {
+	expr := new(ObjectPattern)
+	expr.brace = brace
+	expr.keys = keys
+	expr.values = values
+	return expr
+}
+
+func (expr *ObjectPattern) accept(visitor exprVisitor) (interface{}, error) {
+	return visitor.visitObjectPatternExpr(expr)
+}
+
 type Set struct {
 	object Expr
 	name   *Token
@@ -153,6 +228,22 @@ func (expr *Set) accept(visitor exprVisitor) (interface{}, error) {
 	return visitor.visitSetExpr(expr)
 }
 
+type Spread struct {
+	ellipsis   *Token
+	expression Expr
+}
+
+func NewSpread(ellipsis *Token, expression Expr) *Spread {
+	expr := new(Spread)
+	expr.ellipsis = ellipsis
+	expr.expression = expression
+	return expr
+}
+
+func (expr *Spread) accept(visitor exprVisitor) (interface{}, error) {
+	return visitor.visitSpreadExpr(expr)
+}
+
 type Super struct {
 	keyword *Token
 	method  *Token
@@ -0,0 +1,706 @@
+package glox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Formatter prints a program back as Lox source in the canonical style:
+// four spaces of indentation, one statement per line, opening braces on the
+// line of their statement, and single spaces around binary operators. It
+// keeps the comments and at most one blank line between statements.
+type Formatter struct {
+	info     *sourceInfo
+	comments []*comment
+	indent   int
+	lastLine int
+}
+
+func NewFormatter() *Formatter {
+	return &Formatter{}
+}
+
+// Format parses the source and prints it back.
+func (s *Formatter) Format(source string) (string, error) {
+	scanner := NewScanner(NewTokenMap(), source)
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		return "", err
+	}
+	parser := NewParser(&tokens)
+	statements, err := parser.Parse()
+	if err != nil {
+		return "", err
+	}
+	s.info = parser.info
+	s.comments = scanner.comments
+	s.indent = 0
+	s.lastLine = 0
+	// The end line is past every comment, so the comments after the last statement are kept.
+	return s.lines(s.statementItems(statements), tokens[len(tokens)-1].line+1)
+}
+
+// formatItem is something printed on its own lines, like a statement, a
+// method or a match case.
+type formatItem struct {
+	start int
+	end   int
+	print func() (string, error)
+}
+
+func (s *Formatter) statementItem(stmt Stmt) formatItem {
+	return formatItem{
+		start: s.info.startLine(stmt),
+		end:   s.info.endLine(stmt),
+		print: func() (string, error) {
+			return s.statement(stmt)
+		},
+	}
+}
+
+// lines prints the items one per line with the comments around them, up to
+// the line endLine where the enclosing braces close.
+func (s *Formatter) lines(items []formatItem, endLine int) (string, error) {
+	res := ""
+	first := true
+	for _, item := range items {
+		res += s.leadingComments(item.start, &first)
+		if !first && item.start-s.lastLine > 1 {
+			res += "\n"
+		}
+		str, err := item.print()
+		if err != nil {
+			return "", err
+		}
+		// The comments inside the item that have no place of their own, like
+		// in a multi-line expression, go on their own lines before it.
+		for len(s.comments) > 0 && s.comments[0].line < item.end {
+			res += s.indentation() + s.comments[0].text + "\n"
+			s.comments = s.comments[1:]
+		}
+		if item.end > s.lastLine {
+			s.lastLine = item.end
+		}
+		res += s.indentation() + str + s.trailingComments(s.lastLine) + "\n"
+		first = false
+	}
+	res += s.leadingComments(endLine, &first)
+	return res, nil
+}
+
+// leadingComments prints the comments before the line on their own lines.
+func (s *Formatter) leadingComments(line int, first *bool) string {
+	res := ""
+	for len(s.comments) > 0 && s.comments[0].line < line {
+		c := s.comments[0]
+		s.comments = s.comments[1:]
+		if !*first && c.line-s.lastLine > 1 {
+			res += "\n"
+		}
+		res += s.indentation() + c.text + "\n"
+		s.lastLine = c.endLine
+		*first = false
+	}
+	return res
+}
+
+// trailingComments prints the comments up to the line at the end of the current line.
+func (s *Formatter) trailingComments(line int) string {
+	res := ""
+	for len(s.comments) > 0 && s.comments[0].line <= line {
+		c := s.comments[0]
+		s.comments = s.comments[1:]
+		res += " " + c.text
+		if c.endLine > s.lastLine {
+			s.lastLine = c.endLine
+		}
+	}
+	return res
+}
+
+// formatElement is an element of a list, a map or a call, with the comments
+// on the lines before it and on its last line.
+type formatElement struct {
+	text     string
+	leading  []string
+	trailing string
+}
+
+// elements prints the elements between the brackets, on one line unless
+// comments are written among them: then every element goes on its own line,
+// keeping the comments next to it.
+func (s *Formatter) elements(owner Expr, count int, element func(i int) (string, error), open string, close string) (string, error) {
+	lines := s.info.elements[owner]
+	multiline := false
+	var elements []*formatElement
+	var after []string
+	s.indent++
+	for i := 0; i < count; i++ {
+		text, err := element(i)
+		if err != nil {
+			s.indent--
+			return "", err
+		}
+		e := &formatElement{text: text}
+		multiline = multiline || strings.Contains(text, "\n")
+		// The comments on the line of the closing bracket stay for the
+		// statement.
+		end := 0
+		if lines != nil && lines.ends[i] < lines.close {
+			end = lines.ends[i]
+		} else if lines != nil {
+			end = lines.close - 1
+		}
+		for len(s.comments) > 0 && s.comments[0].line <= end {
+			c := s.comments[0]
+			s.comments = s.comments[1:]
+			if c.line == lines.ends[i] {
+				e.trailing += " " + c.text
+			} else {
+				e.leading = append(e.leading, c.text)
+			}
+			multiline = true
+		}
+		elements = append(elements, e)
+	}
+	for lines != nil && count > 0 && len(s.comments) > 0 && s.comments[0].line < lines.close {
+		after = append(after, s.comments[0].text)
+		s.comments = s.comments[1:]
+		multiline = true
+	}
+	inner := s.indentation()
+	s.indent--
+	if !multiline {
+		var texts []string
+		for _, e := range elements {
+			texts = append(texts, e.text)
+		}
+		return open + strings.Join(texts, ", ") + close, nil
+	}
+	res := open + "\n"
+	for i, e := range elements {
+		for _, c := range e.leading {
+			res += inner + c + "\n"
+		}
+		separator := ","
+		if i == len(elements)-1 {
+			separator = ""
+		}
+		res += inner + e.text + separator + e.trailing + "\n"
+	}
+	for _, c := range after {
+		res += inner + c + "\n"
+	}
+	return res + s.indentation() + close, nil
+}
+
+func (s *Formatter) indentation() string {
+	return strings.Repeat("    ", s.indent)
+}
+
+// block prints braces around the items, the closing one being on endLine in the source.
+func (s *Formatter) block(items []formatItem, endLine int) (string, error) {
+	if len(items) == 0 && (len(s.comments) == 0 || s.comments[0].line >= endLine) {
+		return "{}", nil
+	}
+	s.indent++
+	body, err := s.lines(items, endLine)
+	s.indent--
+	if err != nil {
+		return "", err
+	}
+	return "{\n" + body + s.indentation() + "}", nil
+}
+
+func (s *Formatter) statement(stmt Stmt) (string, error) {
+	if loop, ok := s.info.loops[stmt]; ok {
+		return s.forLoop(loop)
+	}
+	str, err := stmt.accept(s)
+	if err != nil {
+		return "", err
+	}
+	return str.(string), nil
+}
+
+func (s *Formatter) expression(expr Expr) (string, error) {
+	str, err := expr.accept(s)
+	if err != nil {
+		return "", err
+	}
+	return str.(string), nil
+}
+
+func (s *Formatter) expressions(exprs []Expr) (string, error) {
+	var parts []string
+	for _, expr := range exprs {
+		str, err := s.expression(expr)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, str)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// body prints the body of a compound statement, which starts on the line of its header.
+func (s *Formatter) body(stmt Stmt) (string, error) {
+	if block, ok := stmt.(*Block); ok {
+		if _, ok := s.info.loops[stmt]; !ok {
+			return s.block(s.statementItems(*block.statements), s.info.endLine(block))
+		}
+	}
+	return s.statement(stmt)
+}
+
+func (s *Formatter) statementItems(statements []Stmt) []formatItem {
+	items := make([]formatItem, len(statements))
+	for i, stmt := range statements {
+		items[i] = s.statementItem(stmt)
+	}
+	return items
+}
+
+func (s *Formatter) forLoop(loop *forLoop) (string, error) {
+	res := "for ("
+	if loop.initializer == nil {
+		res += ";"
+	} else {
+		str, err := s.statement(loop.initializer)
+		if err != nil {
+			return "", err
+		}
+		res += str
+	}
+	if loop.condition != nil {
+		str, err := s.expression(loop.condition)
+		if err != nil {
+			return "", err
+		}
+		res += " " + str
+	}
+	res += ";"
+	if loop.increment != nil {
+		str, err := s.expression(loop.increment)
+		if err != nil {
+			return "", err
+		}
+		res += " " + str
+	}
+	body, err := s.body(loop.body)
+	if err != nil {
+		return "", err
+	}
+	return res + ") " + body, nil
+}
+
+// function prints a function or a method after the keyword, which ends with a space when not empty.
+func (s *Formatter) function(stmt *Function, keyword string) (string, error) {
+	var params []string
+	for i, param := range *stmt.params {
+		params = append(params, param.lexeme+annotationString((*stmt.paramTypes)[i]))
+	}
+	if stmt.rest != nil {
+		params = append(params, "..."+stmt.rest.lexeme)
+	}
+	res := keyword + stmt.name.lexeme + "(" + strings.Join(params, ", ") + ")" + annotationString(stmt.returnType)
+	if stmt.body == nil {
+		return res + ";", nil
+	}
+	body, err := s.block(s.statementItems(*stmt.body), s.info.endLine(stmt))
+	if err != nil {
+		return "", err
+	}
+	return res + " " + body, nil
+}
+
+// methods prints the methods of a class, an interface or a trait, abstract
+// ones being only allowed in a class.
+func (s *Formatter) methods(methods []*Function, endLine int, abstract bool) (string, error) {
+	items := make([]formatItem, len(methods))
+	for i, method := range methods {
+		method := method
+		keyword := ""
+		if abstract && method.body == nil {
+			keyword = "abstract "
+		}
+		items[i] = formatItem{
+			start: s.info.startLine(method),
+			end:   s.info.endLine(method),
+			print: func() (string, error) {
+				return s.function(method, keyword)
+			},
+		}
+	}
+	return s.block(items, endLine)
+}
+
+func variableNames(variables []*Variable) string {
+	var names []string
+	for _, variable := range variables {
+		names = append(names, variable.name.lexeme)
+	}
+	return strings.Join(names, ", ")
+}
+
+// =====
+
+func (s *Formatter) visitAssignExpr(expr *Assign) (interface{}, error) {
+	value, err := s.expression(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	return expr.name.lexeme + " = " + value, nil
+}
+
+func (s *Formatter) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	pattern, err := s.expression(expr.pattern)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.expression(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	return pattern + " = " + value, nil
+}
+
+func (s *Formatter) visitBinaryExpr(expr *Binary) (interface{}, error) {
+	return s.binary(expr.left, expr.operator, expr.right)
+}
+
+func (s *Formatter) binary(left Expr, operator *Token, right Expr) (interface{}, error) {
+	l, err := s.expression(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.expression(right)
+	if err != nil {
+		return nil, err
+	}
+	return l + " " + operator.lexeme + " " + r, nil
+}
+
+func (s *Formatter) visitCallExpr(expr *Call) (interface{}, error) {
+	callee, err := s.expression(expr.callee)
+	if err != nil {
+		return nil, err
+	}
+	arguments := *expr.arguments
+	return s.elements(expr, len(arguments), func(i int) (string, error) {
+		return s.expression(arguments[i])
+	}, callee+"(", ")")
+}
+
+func (s *Formatter) visitGetExpr(expr *Get) (interface{}, error) {
+	object, err := s.expression(expr.object)
+	if err != nil {
+		return nil, err
+	}
+	return object + "." + expr.name.lexeme, nil
+}
+
+func (s *Formatter) visitGroupingExpr(expr *Grouping) (interface{}, error) {
+	str, err := s.expression(expr.expression)
+	if err != nil {
+		return nil, err
+	}
+	return "(" + str + ")", nil
+}
+
+func (s *Formatter) visitListLiteralExpr(expr *ListLiteral) (interface{}, error) {
+	elements := *expr.elements
+	return s.elements(expr, len(elements), func(i int) (string, error) {
+		return s.expression(elements[i])
+	}, "[", "]")
+}
+
+func (s *Formatter) visitLiteralExpr(expr *Literal) (interface{}, error) {
+	if lexeme, ok := s.info.literals[expr]; ok {
+		return lexeme, nil
+	}
+	switch value := expr.value.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return "\"" + value + "\"", nil
+	}
+	return fmt.Sprint(expr.value), nil
+}
+
+func (s *Formatter) visitLogicalExpr(expr *Logical) (interface{}, error) {
+	return s.binary(expr.left, expr.operator, expr.right)
+}
+
+func (s *Formatter) visitMapLiteralExpr(expr *MapLiteral) (interface{}, error) {
+	return s.elements(expr, len(*expr.keys), func(i int) (string, error) {
+		key := (*expr.keys)[i]
+		k, err := s.expression(key)
+		if err != nil {
+			return "", err
+		}
+		value := (*expr.values)[i]
+		if value == nil {
+			return k, nil
+		}
+		if literal, ok := key.(*Literal); !ok || s.info.literals[literal] == "" {
+			k = "[" + k + "]"
+		}
+		v, err := s.expression(value)
+		if err != nil {
+			return "", err
+		}
+		return k + ": " + v, nil
+	}, "{", "}")
+}
+
+func (s *Formatter) visitObjectPatternExpr(expr *ObjectPattern) (interface{}, error) {
+	var entries []string
+	for i, key := range *expr.keys {
+		value := (*expr.values)[i]
+		if variable, ok := value.(*Variable); ok && variable.name.lexeme == key.lexeme {
+			entries = append(entries, key.lexeme)
+			continue
+		}
+		v, err := s.expression(value)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, key.lexeme+": "+v)
+	}
+	return "{" + strings.Join(entries, ", ") + "}", nil
+}
+
+func (s *Formatter) visitSetExpr(expr *Set) (interface{}, error) {
+	object, err := s.expression(expr.object)
+	if err != nil {
+		return nil, err
+	}
+	value, err := s.expression(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	return object + "." + expr.name.lexeme + " = " + value, nil
+}
+
+func (s *Formatter) visitSpreadExpr(expr *Spread) (interface{}, error) {
+	str, err := s.expression(expr.expression)
+	if err != nil {
+		return nil, err
+	}
+	return "..." + str, nil
+}
+
+func (s *Formatter) visitSuperExpr(expr *Super) (interface{}, error) {
+	return "super." + expr.method.lexeme, nil
+}
+
+func (s *Formatter) visitThisExpr(_ *This) (interface{}, error) {
+	return "this", nil
+}
+
+func (s *Formatter) visitUnaryExpr(expr *Unary) (interface{}, error) {
+	right, err := s.expression(expr.right)
+	if err != nil {
+		return nil, err
+	}
+	return expr.operator.lexeme + right, nil
+}
+
+func (s *Formatter) visitVariableExpr(expr *Variable) (interface{}, error) {
+	return expr.name.lexeme, nil
+}
+
+func (s *Formatter) visitBlockStmt(stmt *Block) (interface{}, error) {
+	return s.body(stmt)
+}
+
+func (s *Formatter) visitClassStmt(stmt *Class) (interface{}, error) {
+	res := "class " + stmt.name.lexeme
+	if stmt.abstract != nil {
+		res = "abstract " + res
+	}
+	if stmt.superclass != nil {
+		res += " < " + stmt.superclass.name.lexeme
+	}
+	if len(*stmt.interfaces) > 0 {
+		res += " implements " + variableNames(*stmt.interfaces)
+	}
+	if len(*stmt.traits) > 0 {
+		res += " with " + variableNames(*stmt.traits)
+	}
+	body, err := s.methods(*stmt.methods, s.info.endLine(stmt), true)
+	if err != nil {
+		return nil, err
+	}
+	return res + " " + body, nil
+}
+
+func (s *Formatter) visitConstStmt(stmt *Const) (interface{}, error) {
+	initializer, err := s.expression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	return "const " + stmt.name.lexeme + " = " + initializer + ";", nil
+}
+
+func (s *Formatter) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	if len(*stmt.members) == 0 {
+		return "enum " + stmt.name.lexeme + " {}", nil
+	}
+	var members []string
+	for _, member := range *stmt.members {
+		members = append(members, member.lexeme)
+	}
+	return "enum " + stmt.name.lexeme + " { " + strings.Join(members, ", ") + " }", nil
+}
+
+func (s *Formatter) visitExpressionStmt(stmt *Expression) (interface{}, error) {
+	str, err := s.expression(stmt.expression)
+	if err != nil {
+		return nil, err
+	}
+	return str + ";", nil
+}
+
+func (s *Formatter) visitFunctionStmt(stmt *Function) (interface{}, error) {
+	return s.function(stmt, "fun ")
+}
+
+func (s *Formatter) visitIfStmt(stmt *If) (interface{}, error) {
+	condition, err := s.expression(stmt.condition)
+	if err != nil {
+		return nil, err
+	}
+	thenBranch, err := s.body(stmt.thenBranch)
+	if err != nil {
+		return nil, err
+	}
+	res := "if (" + condition + ") " + thenBranch
+	if stmt.elseBranch == nil {
+		return res, nil
+	}
+	// The comments after the then branch stay on its last line, before "else".
+	comments := s.trailingComments(s.info.endLine(stmt.thenBranch))
+	if _, ok := stmt.thenBranch.(*Block); ok && comments == "" {
+		res += " else "
+	} else {
+		res += comments + "\n" + s.indentation() + "else "
+	}
+	elseBranch, err := s.body(stmt.elseBranch)
+	if err != nil {
+		return nil, err
+	}
+	return res + elseBranch, nil
+}
+
+func (s *Formatter) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	body, err := s.methods(*stmt.methods, s.info.endLine(stmt), false)
+	if err != nil {
+		return nil, err
+	}
+	return "interface " + stmt.name.lexeme + " " + body, nil
+}
+
+func (s *Formatter) visitMatchStmt(stmt *Match) (interface{}, error) {
+	subject, err := s.expression(stmt.subject)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]formatItem, len(*stmt.cases))
+	for i, matchCase := range *stmt.cases {
+		matchCase := matchCase
+		items[i] = formatItem{
+			start: matchCase.keyword.line,
+			end:   s.info.endLine(matchCase.body),
+			print: func() (string, error) {
+				patterns, err := s.expressions(*matchCase.patterns)
+				if err != nil {
+					return "", err
+				}
+				body, err := s.body(matchCase.body)
+				if err != nil {
+					return "", err
+				}
+				return "case " + patterns + " => " + body, nil
+			},
+		}
+	}
+	body, err := s.block(items, s.info.endLine(stmt))
+	if err != nil {
+		return nil, err
+	}
+	return "match (" + subject + ") " + body, nil
+}
+
+func (s *Formatter) visitPrintStmt(stmt *Print) (interface{}, error) {
+	str, err := s.expression(stmt.expression)
+	if err != nil {
+		return nil, err
+	}
+	return "print " + str + ";", nil
+}
+
+// visitReturnStmt prints back the values of a multiple return, which the
+// Parser gathers in a list literal whose bracket is the return keyword.
+func (s *Formatter) visitReturnStmt(stmt *Return) (interface{}, error) {
+	if stmt.value == nil {
+		return "return;", nil
+	}
+	if list, ok := stmt.value.(*ListLiteral); ok && list.bracket == stmt.keyword {
+		values, err := s.expressions(*list.elements)
+		if err != nil {
+			return nil, err
+		}
+		return "return " + values + ";", nil
+	}
+	value, err := s.expression(stmt.value)
+	if err != nil {
+		return nil, err
+	}
+	return "return " + value + ";", nil
+}
+
+func (s *Formatter) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	body, err := s.methods(*stmt.methods, s.info.endLine(stmt), false)
+	if err != nil {
+		return nil, err
+	}
+	return "trait " + stmt.name.lexeme + " " + body, nil
+}
+
+func (s *Formatter) visitVarStmt(stmt *Var) (interface{}, error) {
+	res := "var " + stmt.name.lexeme + annotationString(stmt.annotation)
+	if stmt.initializer == nil {
+		return res + ";", nil
+	}
+	initializer, err := s.expression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	return res + " = " + initializer + ";", nil
+}
+
+func (s *Formatter) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	pattern, err := s.expression(stmt.pattern)
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := s.expression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	return "var " + pattern + " = " + initializer + ";", nil
+}
+
+func (s *Formatter) visitWhileStmt(stmt *While) (interface{}, error) {
+	condition, err := s.expression(stmt.condition)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.body(stmt.body)
+	if err != nil {
+		return nil, err
+	}
+	return "while (" + condition + ") " + body, nil
+}
@@ -1,79 +1,204 @@
 package glox
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type Glox struct {
 	tokenMap    *map[string]TokenType
 	interpreter *Interpreter
+	profiler    *profiler
+
+	// typeChecker sees every statement of the session, for ":type" to know
+	// the declarations, but its errors only stop a run with checkTypes.
+	typeChecker *TypeChecker
+	checkTypes  bool
+
+	// session holds the REPL inputs that ran successfully.
+	session []string
+
+	input       io.Reader
+	output      io.Writer
+	errorOutput io.Writer
 }
 
 func NewGlox() *Glox {
+	return NewGloxIO(os.Stdin, os.Stdout, os.Stderr)
+}
+
+// NewGloxIO makes an interpreter reading the REPL input from input, and
+// writing what programs print to output and the errors to errorOutput.
+func NewGloxIO(input io.Reader, output io.Writer, errorOutput io.Writer) *Glox {
+	interpreter := NewInterpreter()
+	interpreter.output = output
 	return &Glox{
 		tokenMap:    NewTokenMap(),
-		interpreter: NewInterpreter(),
+		interpreter: interpreter,
+		typeChecker: NewTypeChecker(),
+		input:       input,
+		output:      output,
+		errorOutput: errorOutput,
 	}
 }
 
+// EnableTypeCheck makes the following runs check types before interpreting.
+func (s *Glox) EnableTypeCheck() {
+	s.checkTypes = true
+}
+
+// EnableProfiling makes the following runs record a profile, written by WriteProfile.
+func (s *Glox) EnableProfiling() {
+	s.profiler = newProfiler()
+	s.interpreter.tracer = s.profiler
+}
+
+// WriteProfile writes the profile recorded so far, in the ProfilePprof or the ProfileText format.
+func (s *Glox) WriteProfile(output io.Writer, format string) error {
+	if s.profiler == nil {
+		return errors.New("Profiling isn't enabled.")
+	}
+	return s.profiler.writeProfile(output, format)
+}
+
 func (s *Glox) RunFile(path string) int {
 	fileData, err := os.ReadFile(path)
 	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "[File]", err)
+		_, _ = fmt.Fprintln(s.errorOutput, "[File]", err)
 		return 1
 	}
-	return s.run(string(fileData))
+	if s.profiler != nil {
+		s.profiler.filename = path
+	}
+	return s.run(string(fileData), false)
 }
 
+// RunPrompt reads statements from the standard input. While the input is
+// incomplete, a continuation prompt asks for more lines; an empty line runs
+// the input as it is. Lines starting with ":" are REPL commands. Lines are
+// edited with history, kept in ~/.glox_history, and tab completion.
 func (s *Glox) RunPrompt() int {
-	reader := bufio.NewReader(os.Stdin)
+	historyPath := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyPath = filepath.Join(home, ".glox_history")
+	}
+	editor := NewLineEditor(s.input, s.output, historyPath, s.Complete)
+	source := ""
 	for {
-		fmt.Print("> ")
-		line, err := reader.ReadString('\n')
+		prompt := "> "
+		if source != "" {
+			prompt = "... "
+		}
+		line, err := editor.ReadLine(prompt)
+		if err == ErrInterrupted {
+			source = ""
+			continue
+		}
 		if err != nil {
-			if err.Error() == "EOF" {
+			if err == io.EOF {
+				if strings.TrimSpace(source) != "" {
+					s.runInput(source)
+				}
 				return 0
 			}
-			_, _ = fmt.Fprintln(os.Stderr, "[Prompt]", err)
+			_, _ = fmt.Fprintln(s.errorOutput, "[Prompt]", err)
 			return 1
 		}
-		// if line == "" {
-		//	continue
-		// }
-		s.run(line)
+		if source == "" && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			s.runCommand(line)
+			continue
+		}
+		if source != "" && strings.TrimSpace(line) == "" {
+			s.runInput(source)
+			source = ""
+			continue
+		}
+		source += line + "\n"
+		if strings.TrimSpace(source) == "" {
+			source = ""
+			continue
+		}
+		if !s.IsIncomplete(source) {
+			s.runInput(source)
+			source = ""
+		}
+	}
+}
+
+// runInput runs source entered in the REPL and records it in the session
+// when it succeeds.
+func (s *Glox) runInput(source string) int {
+	code := s.run(source, true)
+	if code == 0 {
+		s.session = append(s.session, source)
 	}
+	return code
 }
 
-func (s *Glox) run(source string) int {
+// IsIncomplete reports whether more lines could complete the source: it has
+// an unterminated string or comment, a doc comment waiting for its
+// declaration, unbalanced brackets, or a parser error at its end.
+func (s *Glox) IsIncomplete(source string) bool {
+	scanner := NewScanner(s.tokenMap, source)
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		lineErr, ok := err.(*LineError)
+		return ok && strings.HasPrefix(lineErr.message, "Unterminated")
+	}
+	if scanner.docLines != nil {
+		return true
+	}
+	depth := 0
+	for _, token := range tokens {
+		switch token.tokenType {
+		case TokenLeftParen, TokenLeftBracket, TokenLeftBrace:
+			depth++
+		case TokenRightParen, TokenRightBracket, TokenRightBrace:
+			depth--
+		}
+	}
+	if depth > 0 {
+		return true
+	}
+	_, err = NewParser(&tokens).Parse()
+	parserErr, ok := err.(*ParserError)
+	return ok && parserErr.token.tokenType == TokenEof
+}
+
+// run runs the source. With echoExpressions, as in the REPL, the value of
+// every top-level expression statement is printed and stored in "_".
+func (s *Glox) run(source string, echoExpressions bool) int {
 	// Scanner
 	scanner := NewScanner(s.tokenMap, source)
 	tokens, err := scanner.ScanTokens()
 	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "[Scanner]", err.Error())
+		_, _ = fmt.Fprintln(s.errorOutput, "[Scanner]", err.Error())
 		return 1
 	}
 	currentLine := 0
 	for _, token := range tokens {
 		if token.line != currentLine {
 			if currentLine != 0 {
-				fmt.Println()
+				_, _ = fmt.Fprintln(s.output)
 			}
 			currentLine = token.line
-			fmt.Print(fmt.Sprintf("[Scanner | %v] ", currentLine))
+			_, _ = fmt.Fprintf(s.output, "[Scanner | %v] ", currentLine)
 		}
 		// fmt.Println(token.String())
-		fmt.Print(token.Lexeme())
-		fmt.Print(" | ")
+		_, _ = fmt.Fprint(s.output, token.Lexeme())
+		_, _ = fmt.Fprint(s.output, " | ")
 	}
-	fmt.Println()
+	_, _ = fmt.Fprintln(s.output)
 
 	// Parser
 	parser := NewParser(&tokens)
 	statements, err := parser.Parse()
 	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "[Parser]", err.Error())
+		_, _ = fmt.Fprintln(s.errorOutput, "[Parser]", err.Error())
 		return 1
 	}
 
@@ -82,29 +207,61 @@ func (s *Glox) run(source string) int {
 	for _, statement := range statements {
 		astStr, err := astPrinter.PrintStatement(statement)
 		if err != nil {
-			_, _ = fmt.Fprintln(os.Stderr, "[AST]", err.Error())
+			_, _ = fmt.Fprintln(s.errorOutput, "[AST]", err.Error())
 			return 1
 		}
-		fmt.Println("[AST]", astStr)
+		_, _ = fmt.Fprintln(s.output, "[AST]", astStr)
 	}
 
 	// Resolver
 	resolver := NewResolver(s.interpreter)
 	err = resolver.resolveStatements(&statements)
+	for _, warning := range resolver.Warnings() {
+		_, _ = fmt.Fprintln(s.errorOutput, "[Resolver]", warning.String())
+	}
 	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "[Resolver]", err.Error())
+		_, _ = fmt.Fprintln(s.errorOutput, "[Resolver]", err.Error())
 		return 1
 	}
 
+	// Type Checker
+	if s.checkTypes {
+		err = s.typeChecker.checkStatements(&statements)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.errorOutput, "[TypeChecker]", err.Error())
+			return 1
+		}
+	} else {
+		for _, statement := range statements {
+			_, _ = statement.accept(s.typeChecker)
+		}
+	}
+
 	// Interpreter
-	// err = s.interpreter.Interpret(statements)
-	value, err := s.interpreter.Interpret(&statements)
+	if s.profiler != nil {
+		s.profiler.addProgram(parser.info, statements)
+	}
+	var echo func(value interface{}) error
+	if echoExpressions {
+		echo = s.echo
+	}
+	err = s.interpreter.Interpret(&statements, echo)
 	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, "[Interpreter]", err.Error())
+		_, _ = fmt.Fprintln(s.errorOutput, "[Interpreter]", err.Error())
 		return 1
 	}
-	if value != nil {
-		fmt.Println("[Expression]", s.interpreter.Stringify(value))
-	}
 	return 0
 }
+
+func (s *Glox) echo(value interface{}) error {
+	str, err := s.interpreter.stringify(value)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(s.output, "=>", str)
+	if s.interpreter.globals.constants["_"] {
+		// A "const _" declared in the session is kept.
+		return nil
+	}
+	return s.interpreter.globals.define("_", value)
+}
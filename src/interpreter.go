@@ -2,6 +2,8 @@ package glox
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 )
 
@@ -9,15 +11,49 @@ type Interpreter struct {
 	globals     *Environment
 	environment *Environment
 	locals      map[Expr]int
+	privates    map[Expr]*Class
+	decimals    *decimalContext
+
+	// output receives what print statements write.
+	output io.Writer
+
+	// tracer, when set, follows the execution for the debugger or the profiler.
+	tracer tracer
+}
+
+// tracer is told about every statement before it runs, and about every call
+// of a LoxFunction.
+type tracer interface {
+	beforeStatement(stmt Stmt) error
+	enterFunction(function *LoxFunction, environment *Environment)
+	leaveFunction()
 }
 
 func NewInterpreter() *Interpreter {
 	environment := NewEnvironment(nil)
 	_ = environment.define("clock", NewClockLoxFunction())
+	_ = environment.define("freeze", NewFreezeLoxFunction())
+	_ = environment.define("div", NewDivLoxFunction())
+	_ = environment.define("setDecimalPrecision", NewSetDecimalPrecisionLoxFunction())
+	_ = environment.define("setDecimalRounding", NewSetDecimalRoundingLoxFunction())
+	_ = environment.define("typeOf", NewTypeOfLoxFunction())
+	_ = environment.define("classOf", NewClassOfLoxFunction())
+	_ = environment.define("instanceOf", NewInstanceOfLoxFunction())
+	_ = environment.define("fields", NewFieldsLoxFunction())
+	_ = environment.define("methods", NewMethodsLoxFunction())
+	_ = environment.define("hasField", NewHasFieldLoxFunction())
+	_ = environment.define("getField", NewGetFieldLoxFunction())
+	_ = environment.define("setField", NewSetFieldLoxFunction())
+	_ = environment.define("arity", NewArityLoxFunction())
+	_ = environment.define("nameOf", NewNameOfLoxFunction())
+	_ = environment.define("docOf", NewDocOfLoxFunction())
 	return &Interpreter{
 		globals:     environment,
 		environment: environment,
 		locals:      map[Expr]int{},
+		privates:    map[Expr]*Class{},
+		decimals:    newDecimalContext(),
+		output:      os.Stdout,
 	}
 }
 
@@ -29,33 +65,96 @@ func (s *Interpreter) InterpretExpressionForTest(expr Expr) (interface{}, error)
 	return value, err
 }
 
-func (s *Interpreter) Interpret(statements *[]Stmt) (interface{}, error) {
-	var value interface{}
+// Interpret executes the statements. When echo isn't nil, it receives the
+// value of every top-level expression statement.
+func (s *Interpreter) Interpret(statements *[]Stmt, echo func(value interface{}) error) error {
 	for _, stmt := range *statements {
-		var err error
-		value, err = s.execute(stmt)
+		value, err := s.execute(stmt)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if _, ok := stmt.(*Expression); ok && echo != nil {
+			err = echo(value)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return value, nil
+	return nil
 }
 
 func (s *Interpreter) execute(stmt Stmt) (interface{}, error) {
+	if s.tracer != nil {
+		err := s.tracer.beforeStatement(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return stmt.accept(s)
 }
 
+// Stringify formats a value for display. If a toString method fails,
+// the value is formatted as if its class had none.
 func (s *Interpreter) Stringify(obj interface{}) string {
+	str, err := s.stringify(obj)
+	if err != nil {
+		return fmt.Sprint(obj)
+	}
+	return str
+}
+
+func (s *Interpreter) stringify(obj interface{}) (string, error) {
 	if obj == nil {
-		return "nil"
+		return "nil", nil
 	}
-	if isFloat64(obj) {
-		return fmt.Sprintf("%v", obj.(float64))
+	if isNumber(obj) {
+		return formatNumber(obj), nil
 	}
 	if isBool(obj) {
-		return fmt.Sprintf("%v", obj.(bool))
+		return fmt.Sprintf("%v", obj.(bool)), nil
+	}
+	if list, ok := obj.(*LoxList); ok {
+		str := "["
+		for i, element := range list.elements {
+			if i != 0 {
+				str += ", "
+			}
+			element, err := s.stringify(element)
+			if err != nil {
+				return "", err
+			}
+			str += element
+		}
+		return str + "]", nil
+	}
+	if m, ok := obj.(*LoxMap); ok {
+		str := "{"
+		for i, key := range m.keys {
+			if i != 0 {
+				str += ", "
+			}
+			k, err := s.stringify(key)
+			if err != nil {
+				return "", err
+			}
+			v, err := s.stringify(m.values[m.slots[i]])
+			if err != nil {
+				return "", err
+			}
+			str += k + ": " + v
+		}
+		return str + "}", nil
+	}
+	value, ok, err := s.callHook(obj, "toString")
+	if err != nil || !ok {
+		return fmt.Sprint(obj), err
 	}
-	return fmt.Sprint(obj)
+	str, ok := value.(string)
+	if !ok {
+		method := obj.(*LoxInstance).class.findMethod("toString")
+		return "", NewRuntimeError(method.declaration.name, "'toString' must return a string.")
+	}
+	return str, nil
 }
 
 // =====
@@ -74,7 +173,23 @@ func (s *Interpreter) visitClassStmt(stmt *Class) (interface{}, error) {
 			return nil, NewRuntimeError(stmt.superclass.name, "Superclass must be a class.")
 		}
 	}
-	err = s.environment.define(stmt.name.lexeme, nil)
+	var interfaces []*LoxInterface
+	for _, variable := range *stmt.interfaces {
+		value, err := s.evaluate(variable)
+		if err != nil {
+			return nil, err
+		}
+		iface, ok := value.(*LoxInterface)
+		if !ok {
+			return nil, NewRuntimeError(variable.name, "Can only implement interfaces.")
+		}
+		interfaces = append(interfaces, iface)
+	}
+	methods, err := s.traitMethods(stmt)
+	if err != nil {
+		return nil, err
+	}
+	err = s.environment.declare(stmt.name, nil, false)
 	if err != nil {
 		return nil, err
 	}
@@ -85,18 +200,29 @@ func (s *Interpreter) visitClassStmt(stmt *Class) (interface{}, error) {
 			return nil, err
 		}
 	}
-	methods := make(map[string]*LoxFunction)
+	privateMethods := make(map[string]*LoxFunction)
 	for _, method := range *stmt.methods {
+		if method.body == nil {
+			continue
+		}
+		if method.name.tokenType == TokenPrivateIdentifier {
+			privateMethods[method.name.lexeme] = NewLoxFunction(method, s.environment, false)
+			continue
+		}
 		methods[method.name.lexeme] = NewLoxFunction(method, s.environment, method.name.lexeme == "init")
 	}
 	var sClass *LoxClass
 	if superclass != nil {
 		sClass = superclass.(*LoxClass)
 	}
-	class := NewLoxClass(stmt.name.lexeme, sClass, &methods)
+	class := NewLoxClass(stmt, sClass, interfaces, &methods, &privateMethods)
 	if superclass != nil {
 		s.environment = s.environment.enclosing
 	}
+	err = class.checkContract()
+	if err != nil {
+		return nil, err
+	}
 	err = s.environment.assign(stmt.name, class)
 	if err != nil {
 		return nil, err
@@ -104,13 +230,67 @@ func (s *Interpreter) visitClassStmt(stmt *Class) (interface{}, error) {
 	return nil, nil
 }
 
+func (s *Interpreter) visitConstStmt(stmt *Const) (interface{}, error) {
+	value, err := s.evaluate(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.environment.declare(stmt.name, value, true)
+}
+
+func (s *Interpreter) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	var memberNames []string
+	for _, member := range *stmt.members {
+		memberNames = append(memberNames, member.lexeme)
+	}
+	return nil, s.environment.declare(stmt.name, NewLoxEnum(stmt.name.lexeme, memberNames), false)
+}
+
+// traitMethods collects the methods mixed into a class by its traits.
+// A trait can only be listed once, and a method provided by several traits
+// must be overridden by the class.
+func (s *Interpreter) traitMethods(stmt *Class) (map[string]*LoxFunction, error) {
+	methods := make(map[string]*LoxFunction)
+	providers := make(map[string]*LoxTrait)
+	mixed := make(map[*LoxTrait]bool)
+	overridden := make(map[string]bool)
+	for _, method := range *stmt.methods {
+		overridden[method.name.lexeme] = true
+	}
+	for _, variable := range *stmt.traits {
+		value, err := s.evaluate(variable)
+		if err != nil {
+			return nil, err
+		}
+		trait, ok := value.(*LoxTrait)
+		if !ok {
+			return nil, NewRuntimeError(variable.name, "Can only mix in traits.")
+		}
+		if mixed[trait] {
+			return nil, NewRuntimeError(variable.name, fmt.Sprintf(
+				"Trait '%v' is mixed into class '%v' more than once.", trait.name, stmt.name.lexeme))
+		}
+		mixed[trait] = true
+		for name, method := range *trait.methods {
+			if provider, ok := providers[name]; ok && !overridden[name] {
+				return nil, NewRuntimeError(variable.name, fmt.Sprintf(
+					"Method '%v' is provided by both '%v' and '%v'; class '%v' must override it.",
+					name, provider.name, trait.name, stmt.name.lexeme))
+			}
+			providers[name] = trait
+			methods[name] = method
+		}
+	}
+	return methods, nil
+}
+
 func (s *Interpreter) visitExpressionStmt(stmt *Expression) (interface{}, error) {
 	return s.evaluate(stmt.expression)
 }
 
 func (s *Interpreter) visitFunctionStmt(stmt *Function) (interface{}, error) {
 	function := NewLoxFunction(stmt, s.environment, false)
-	return nil, s.environment.define(stmt.name.lexeme, function)
+	return nil, s.environment.declare(stmt.name, function, false)
 }
 
 func (s *Interpreter) visitIfStmt(stmt *If) (interface{}, error) {
@@ -126,12 +306,48 @@ func (s *Interpreter) visitIfStmt(stmt *If) (interface{}, error) {
 	return nil, nil
 }
 
+func (s *Interpreter) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	return nil, s.environment.declare(stmt.name, NewLoxInterface(stmt.name.lexeme, stmt.methods), false)
+}
+
+func (s *Interpreter) visitMatchStmt(stmt *Match) (interface{}, error) {
+	subject, err := s.evaluate(stmt.subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, matchCase := range *stmt.cases {
+		for _, pattern := range *matchCase.patterns {
+			var bindings []patternBinding
+			matched, err := s.matchPattern(pattern, subject, &bindings)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			environment := NewEnvironment(s.environment)
+			for _, binding := range bindings {
+				err = environment.define(binding.target.(*Variable).name.lexeme, binding.value)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return nil, s.executeBlock(&[]Stmt{matchCase.body}, environment)
+		}
+	}
+	return nil, nil
+}
+
 func (s *Interpreter) visitPrintStmt(stmt *Print) (interface{}, error) {
 	value, err := s.evaluate(stmt.expression)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("[Print]", s.Stringify(value))
+	str, err := s.stringify(value)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = fmt.Fprintln(s.output, "[Print]", str)
 	return nil, nil
 }
 
@@ -147,6 +363,14 @@ func (s *Interpreter) visitReturnStmt(stmt *Return) (interface{}, error) {
 	return nil, NewReturnPseudoError(value)
 }
 
+func (s *Interpreter) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	methods := make(map[string]*LoxFunction)
+	for _, method := range *stmt.methods {
+		methods[method.name.lexeme] = NewLoxFunction(method, s.environment, method.name.lexeme == "init")
+	}
+	return nil, s.environment.declare(stmt.name, NewLoxTrait(stmt.name.lexeme, &methods), false)
+}
+
 func (s *Interpreter) visitVarStmt(stmt *Var) (interface{}, error) {
 	var value interface{} = nil
 	if stmt.initializer != nil {
@@ -156,7 +380,26 @@ func (s *Interpreter) visitVarStmt(stmt *Var) (interface{}, error) {
 			return nil, err
 		}
 	}
-	return nil, s.environment.define(stmt.name.lexeme, value)
+	return nil, s.environment.declare(stmt.name, value, false)
+}
+
+func (s *Interpreter) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	value, err := s.evaluate(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	var bindings []patternBinding
+	err = s.destructure(stmt.pattern, value, stmt.keyword, &bindings)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings {
+		err = s.environment.declare(binding.target.(*Variable).name, binding.value, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
 }
 
 func (s *Interpreter) visitWhileStmt(stmt *While) (interface{}, error) {
@@ -209,14 +452,39 @@ func (s *Interpreter) visitAssignExpr(expr *Assign) (interface{}, error) {
 	// }
 	// return value, nil
 
-	if distance, ok := s.locals[expr]; ok {
-		err = s.environment.assignAt(distance, expr.name, value)
-	} else {
-		err = s.globals.assign(expr.name, value)
+	err = s.assignVariable(expr, expr.name, value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *Interpreter) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	value, err := s.evaluate(expr.value)
+	if err != nil {
+		return nil, err
 	}
+	var bindings []patternBinding
+	err = s.destructure(expr.pattern, value, expr.equals, &bindings)
 	if err != nil {
 		return nil, err
 	}
+	for _, binding := range bindings {
+		switch target := binding.target.(type) {
+		case *Variable:
+			err = s.assignVariable(target, target.name, binding.value)
+		case *Get:
+			var obj interface{}
+			obj, err = s.evaluate(target.object)
+			if err != nil {
+				return nil, err
+			}
+			err = s.setProperty(target, obj, target.name, binding.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
 	return value, nil
 }
 
@@ -230,55 +498,30 @@ func (s *Interpreter) visitBinaryExpr(expr *Binary) (interface{}, error) {
 		return nil, err
 	}
 	switch expr.operator.tokenType {
-	case TokenGreater:
-		err = s.checkNumberOperands(expr.operator, left, right)
-		if err != nil {
-			return nil, err
-		}
-		return left.(float64) > right.(float64), nil
-	case TokenGreaterEqual:
-		err = s.checkNumberOperands(expr.operator, left, right)
-		if err != nil {
-			return nil, err
-		}
-		return left.(float64) >= right.(float64), nil
-	case TokenLess:
-		err = s.checkNumberOperands(expr.operator, left, right)
-		if err != nil {
-			return nil, err
-		}
-		return left.(float64) < right.(float64), nil
-	case TokenLessEqual:
+	case TokenGreater, TokenGreaterEqual, TokenLess, TokenLessEqual:
 		err = s.checkNumberOperands(expr.operator, left, right)
 		if err != nil {
 			return nil, err
 		}
-		return left.(float64) <= right.(float64), nil
+		return compareNumbers(expr.operator.tokenType, left, right), nil
 	case TokenBangEqual:
-		return !s.isEqual(left, right), nil
+		equal, err := s.isEqual(left, right)
+		return !equal, err
 	case TokenEqualEqual:
-		return s.isEqual(left, right), nil
-	case TokenMinus:
+		return s.isEqual(left, right)
+	case TokenMinus, TokenSlash, TokenStar:
 		err = s.checkNumberOperands(expr.operator, left, right)
 		if err != nil {
 			return nil, err
 		}
-		return left.(float64) - right.(float64), nil
-	case TokenSlash:
-		err = s.checkNumberOperands(expr.operator, left, right)
-		if err != nil {
-			return nil, err
-		}
-		return left.(float64) / right.(float64), nil
-	case TokenStar:
-		err = s.checkNumberOperands(expr.operator, left, right)
-		if err != nil {
-			return nil, err
-		}
-		return left.(float64) * right.(float64), nil
+		return arithmetic(expr.operator, left, right, s.decimals)
 	case TokenPlus:
-		if isFloat64(left) && isFloat64(right) {
-			return left.(float64) + right.(float64), nil
+		if isNumber(left) && isNumber(right) {
+			err = s.checkNumberOperands(expr.operator, left, right)
+			if err != nil {
+				return nil, err
+			}
+			return arithmetic(expr.operator, left, right, s.decimals)
 		}
 		if isString(left) && isString(right) {
 			return left.(string) + right.(string), nil
@@ -293,33 +536,75 @@ func (s *Interpreter) visitCallExpr(expr *Call) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	var arguments []interface{}
-	for _, argument := range *expr.arguments {
-		expr, err := s.evaluate(argument)
-		if err != nil {
-			return nil, err
-		}
-		arguments = append(arguments, expr)
+	arguments, err := s.evaluateElements(expr.arguments)
+	if err != nil {
+		return nil, err
 	}
 	if function, ok := callee.(LoxCallable); ok {
-		if len(arguments) != function.arity() {
+		if !checkArity(function, len(arguments)) {
+			expected := "Expected"
+			if v, ok := function.(variadicCallable); ok && v.isVariadic() {
+				expected += " at least"
+			}
 			return nil, NewRuntimeError(expr.paren,
-				fmt.Sprintf("Expected %v arguments but got %v.", function.arity(), len(arguments)))
+				fmt.Sprintf("%v %v arguments but got %v.", expected, function.arity(), len(arguments)))
 		}
-		return function.call(s, &arguments)
+		value, err := function.call(s, &arguments)
+		if nativeErr, ok := err.(*NativeError); ok {
+			return nil, NewRuntimeError(expr.paren, nativeErr.message)
+		}
+		return value, err
 	} else {
 		return nil, NewRuntimeError(expr.paren, "Can only call functions and classes.")
 	}
 }
 
+// evaluateElements evaluates arguments or list elements, expanding spread lists in place.
+func (s *Interpreter) evaluateElements(exprs *[]Expr) ([]interface{}, error) {
+	var values []interface{}
+	for _, expr := range *exprs {
+		if spread, ok := expr.(*Spread); ok {
+			value, err := s.evaluate(spread.expression)
+			if err != nil {
+				return nil, err
+			}
+			list, ok := value.(*LoxList)
+			if !ok {
+				return nil, NewRuntimeError(spread.ellipsis, "Can only spread lists.")
+			}
+			values = append(values, list.elements...)
+			continue
+		}
+		value, err := s.evaluate(expr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
 func (s *Interpreter) visitGetExpr(expr *Get) (interface{}, error) {
 	obj, err := s.evaluate(expr.object)
 	if err != nil {
 		return nil, err
 	}
+	if expr.name.tokenType == TokenPrivateIdentifier {
+		instance, owner, err := s.privateOwner(expr, obj, expr.name)
+		if err != nil {
+			return nil, err
+		}
+		return instance.getPrivate(owner, expr.name)
+	}
 	if v, ok := obj.(*LoxInstance); ok {
 		return v.get(expr.name)
 	}
+	if v, ok := obj.(*LoxEnum); ok {
+		return v.get(expr.name)
+	}
+	if v, ok := obj.(*LoxEnumMember); ok {
+		return v.get(expr.name)
+	}
 	return nil, NewRuntimeError(expr.name, "Only instances have properties.")
 }
 
@@ -327,6 +612,14 @@ func (s *Interpreter) visitGroupingExpr(expr *Grouping) (interface{}, error) {
 	return s.evaluate(expr.expression)
 }
 
+func (s *Interpreter) visitListLiteralExpr(expr *ListLiteral) (interface{}, error) {
+	elements, err := s.evaluateElements(expr.elements)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoxList(elements), nil
+}
+
 func (s *Interpreter) visitLiteralExpr(expr *Literal) (interface{}, error) {
 	return expr.value, nil
 }
@@ -346,21 +639,67 @@ func (s *Interpreter) visitLogicalExpr(expr *Logical) (interface{}, error) {
 	return s.evaluate(expr.right)
 }
 
+func (s *Interpreter) visitMapLiteralExpr(expr *MapLiteral) (interface{}, error) {
+	m := NewLoxMap()
+	for i, key := range *expr.keys {
+		if spread, ok := key.(*Spread); ok {
+			value, err := s.evaluate(spread.expression)
+			if err != nil {
+				return nil, err
+			}
+			base, ok := value.(*LoxMap)
+			if !ok {
+				return nil, NewRuntimeError(spread.ellipsis, "Can only spread maps into a map literal.")
+			}
+			for i, k := range base.keys {
+				err = m.set(s, k, base.values[base.slots[i]])
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		k, err := s.evaluate(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := s.evaluate((*expr.values)[i])
+		if err != nil {
+			return nil, err
+		}
+		err = m.set(s, k, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (s *Interpreter) visitObjectPatternExpr(expr *ObjectPattern) (interface{}, error) {
+	return nil, NewRuntimeError(expr.brace, "Object patterns can only be used in destructuring and match cases.")
+}
+
 func (s *Interpreter) visitSetExpr(expr *Set) (interface{}, error) {
 	obj, err := s.evaluate(expr.object)
 	if err != nil {
 		return nil, err
 	}
-	if o, ok := obj.(*LoxInstance); !ok {
+	if _, ok := obj.(*LoxInstance); !ok {
 		return nil, NewRuntimeError(expr.name, "Only instances have fields.")
-	} else {
-		value, err := s.evaluate(expr.value)
-		if err != nil {
-			return nil, err
-		}
-		o.set(expr.name, value)
-		return value, nil
 	}
+	value, err := s.evaluate(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	err = s.setProperty(expr, obj, expr.name, value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *Interpreter) visitSpreadExpr(expr *Spread) (interface{}, error) {
+	return nil, NewRuntimeError(expr.ellipsis, "Can only spread inside calls and list or map literals.")
 }
 
 func (s *Interpreter) visitSuperExpr(expr *Super) (interface{}, error) {
@@ -397,7 +736,7 @@ func (s *Interpreter) visitUnaryExpr(expr *Unary) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
-		return -right.(float64), nil
+		return negate(right), nil
 	}
 	return nil, nil
 }
@@ -413,6 +752,45 @@ func (s *Interpreter) resolve(expr Expr, depth int) {
 	s.locals[expr] = depth
 }
 
+func (s *Interpreter) resolvePrivate(expr Expr, owner *Class) {
+	s.privates[expr] = owner
+}
+
+// privateOwner finds the class in the instance's hierarchy that declares
+// the class body a private access was resolved in.
+func (s *Interpreter) privateOwner(expr Expr, obj interface{}, name *Token) (*LoxInstance, *LoxClass, error) {
+	declaration := s.privates[expr]
+	if instance, ok := obj.(*LoxInstance); ok && declaration != nil {
+		for class := instance.class; class != nil; class = class.superclass {
+			if class.declaration == declaration {
+				return instance, class, nil
+			}
+		}
+	}
+	return nil, nil, NewRuntimeError(name, "Can't access private member '"+name.lexeme+"' outside of its class.")
+}
+
+func (s *Interpreter) setProperty(expr Expr, obj interface{}, name *Token, value interface{}) error {
+	if name.tokenType == TokenPrivateIdentifier {
+		instance, owner, err := s.privateOwner(expr, obj, name)
+		if err != nil {
+			return err
+		}
+		return instance.setPrivate(owner, name, value)
+	}
+	if o, ok := obj.(*LoxInstance); ok {
+		return o.set(name, value)
+	}
+	return NewRuntimeError(name, "Only instances have fields.")
+}
+
+func (s *Interpreter) assignVariable(expr Expr, name *Token, value interface{}) error {
+	if distance, ok := s.locals[expr]; ok {
+		return s.environment.assignAt(distance, name, value)
+	}
+	return s.globals.assign(name, value)
+}
+
 func (s *Interpreter) lookUpVariable(name *Token, expr Expr) (interface{}, error) {
 	if distance, ok := s.locals[expr]; ok {
 		return s.environment.getAt(distance, name.lexeme)
@@ -433,29 +811,68 @@ func (s *Interpreter) isTruthy(obj interface{}) bool {
 	return true
 }
 
-func (s *Interpreter) isEqual(a interface{}, b interface{}) bool {
+func (s *Interpreter) isEqual(a interface{}, b interface{}) (bool, error) {
 	if a == nil && b == nil {
-		return true
+		return true, nil
 	}
-	if a == nil {
-		return false
+	if isNumber(a) && isNumber(b) {
+		return numbersEqual(a, b), nil
+	}
+	if a == b {
+		return true, nil
 	}
-	return a == b
+	// Either operand's "equals" decides, so that == is symmetric.
+	value, ok, err := s.callHook(a, "equals", b)
+	if err == nil && !ok {
+		value, ok, err = s.callHook(b, "equals", a)
+	}
+	if err != nil || !ok {
+		return false, err
+	}
+	return s.isTruthy(value), nil
+}
+
+// callHook calls the named method on an instance whose class defines it.
+// It reports false when the value has no such method.
+func (s *Interpreter) callHook(obj interface{}, name string, arguments ...interface{}) (interface{}, bool, error) {
+	instance, ok := obj.(*LoxInstance)
+	if !ok {
+		return nil, false, nil
+	}
+	method := instance.class.findMethod(name)
+	if method == nil {
+		return nil, false, nil
+	}
+	if !checkArity(method, len(arguments)) {
+		return nil, false, NewRuntimeError(method.declaration.name,
+			fmt.Sprintf("'%v' must accept %v argument(s).", name, len(arguments)))
+	}
+	bound, err := method.bind(instance)
+	if err != nil {
+		return nil, false, err
+	}
+	value, err := bound.call(s, &arguments)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
 }
 
 func (s *Interpreter) checkNumberOperands(operator *Token, operands ...interface{}) error {
+	hasDecimal, hasFloat := false, false
 	for _, operand := range operands {
-		if !isFloat64(operand) {
+		if !isNumber(operand) {
 			return NewRuntimeError(operator, "Operand must be a number.")
 		}
+		hasDecimal = hasDecimal || isDecimal(operand)
+		hasFloat = hasFloat || isFloat(operand)
+	}
+	if hasDecimal && hasFloat {
+		return NewRuntimeError(operator, "Can't mix decimal and float numbers.")
 	}
 	return nil
 }
 
-func isFloat64(obj interface{}) bool {
-	return reflect.ValueOf(obj).Kind() == reflect.Float64
-}
-
 func isBool(obj interface{}) bool {
 	return reflect.ValueOf(obj).Kind() == reflect.Bool
 }
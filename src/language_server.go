@@ -0,0 +1,847 @@
+package glox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// LanguageServer speaks the Language Server Protocol over a pair of streams,
+// usually stdin and stdout. Documents are synced whole, and analyzed again on
+// every change. Positions count characters in UTF-16 code units, as the
+// protocol requires, unless the client accepts code points ("utf-32").
+type LanguageServer struct {
+	input     *bufio.Reader
+	output    io.Writer
+	documents map[string]*document
+	shutdown  bool
+	utf16     bool
+}
+
+// document is an open text document, with what the last analysis of it found.
+type document struct {
+	text         string
+	lines        []string
+	utf16        bool
+	statements   []Stmt
+	info         *sourceInfo
+	symbols      *symbolIndex
+	declarations map[*Token]*declaration
+	outline      []*declaration
+	diagnostics  []*lspDiagnostic
+}
+
+// declaration is a name declared in a document, described for hovers, symbols and completions.
+type declaration struct {
+	name     *Token
+	kind     int
+	detail   string
+	doc      string
+	stmt     Stmt
+	children []*declaration
+}
+
+// The LSP symbol kinds used for declarations.
+const (
+	symbolClass      = 5
+	symbolMethod     = 6
+	symbolEnum       = 10
+	symbolInterface  = 11
+	symbolFunction   = 12
+	symbolVariable   = 13
+	symbolConstant   = 14
+	symbolEnumMember = 22
+)
+
+// The JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *rpcError) Error() string {
+	return s.Message
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string               `json:"name"`
+	Detail         string               `json:"detail"`
+	Kind           int                  `json:"kind"`
+	Range          lspRange             `json:"range"`
+	SelectionRange lspRange             `json:"selectionRange"`
+	Children       []*lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+func NewLanguageServer(input io.Reader, output io.Writer) *LanguageServer {
+	return &LanguageServer{
+		input:     bufio.NewReader(input),
+		output:    output,
+		documents: map[string]*document{},
+		utf16:     true,
+	}
+}
+
+// Serve answers the messages of the client until it sends "exit" or closes the input.
+// A message that isn't valid JSON is answered with an error; only a broken frame ends the loop.
+func (s *LanguageServer) Serve() error {
+	for {
+		message, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			err = s.write(map[string]interface{}{"jsonrpc": "2.0", "id": nil, "error": rpcErr})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if message.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("Exit without shutdown.")
+			}
+			return nil
+		}
+		result, err := s.handle(message)
+		if len(message.ID) == 0 {
+			continue
+		}
+		if err != nil {
+			var rpcErr *rpcError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+			err = s.write(map[string]interface{}{"jsonrpc": "2.0", "id": message.ID, "error": rpcErr})
+		} else {
+			err = s.write(map[string]interface{}{"jsonrpc": "2.0", "id": message.ID, "result": result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LanguageServer) read() (*rpcMessage, error) {
+	body, err := readMessage(s.input)
+	if err != nil {
+		return nil, err
+	}
+	message := &rpcMessage{}
+	err = json.Unmarshal(body, message)
+	if err != nil {
+		return nil, &rpcError{Code: rpcParseError, Message: err.Error()}
+	}
+	return message, nil
+}
+
+func (s *LanguageServer) write(message interface{}) error {
+	return writeMessage(s.output, message)
+}
+
+func (s *LanguageServer) notify(method string, params interface{}) error {
+	return s.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *LanguageServer) handle(message *rpcMessage) (interface{}, error) {
+	if s.shutdown {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "The server is shut down."}
+	}
+	switch message.Method {
+	case "initialize":
+		return s.initialize(message.Params)
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		return s.didOpen(message.Params)
+	case "textDocument/didChange":
+		return s.didChange(message.Params)
+	case "textDocument/didClose":
+		return s.didClose(message.Params)
+	case "textDocument/definition":
+		return s.definition(message.Params)
+	case "textDocument/references":
+		return s.references(message.Params)
+	case "textDocument/hover":
+		return s.hover(message.Params)
+	case "textDocument/documentSymbol":
+		return s.documentSymbol(message.Params)
+	case "textDocument/completion":
+		return s.completion(message.Params)
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "Unknown method '" + message.Method + "'."}
+}
+
+// initialize picks code points for the positions when the client accepts them,
+// and UTF-16 code units otherwise.
+func (s *LanguageServer) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	err := json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, err
+	}
+	encoding := "utf-16"
+	for _, e := range p.Capabilities.General.PositionEncodings {
+		if e == "utf-32" {
+			encoding = e
+		}
+	}
+	s.utf16 = encoding == "utf-16"
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"positionEncoding":       encoding,
+			"textDocumentSync":       1,
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{"."},
+			},
+		},
+		"serverInfo": map[string]interface{}{
+			"name": "glox",
+		},
+	}, nil
+}
+
+// =====
+
+func (s *LanguageServer) didOpen(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+	}
+	err := json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+func (s *LanguageServer) didChange(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	err := json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	return nil, s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+}
+
+func (s *LanguageServer) didClose(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	err := json.Unmarshal(params, &p)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.documents, p.TextDocument.URI)
+	return nil, s.publishDiagnostics(p.TextDocument.URI, []*lspDiagnostic{})
+}
+
+// update analyzes the new text of a document and publishes its diagnostics.
+// While the text doesn't parse, like in the middle of typing, the last
+// analysis that went through stands for it.
+func (s *LanguageServer) update(uri string, text string) error {
+	doc := analyze(text, s.utf16)
+	if previous, ok := s.documents[uri]; ok && doc.info == nil && previous.info != nil {
+		doc.keep(previous)
+	}
+	s.documents[uri] = doc
+	return s.publishDiagnostics(uri, doc.diagnostics)
+}
+
+func (s *LanguageServer) publishDiagnostics(uri string, diagnostics []*lspDiagnostic) error {
+	return s.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+// lookup returns the open document and the declaration of the name at the
+// position, which is nil when there is no name there or it isn't declared in
+// the document.
+func (s *LanguageServer) lookup(params json.RawMessage) (*lspTextDocumentPosition, *document, *Token, error) {
+	p := &lspTextDocumentPosition{}
+	err := json.Unmarshal(params, p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, nil, nil, &rpcError{Code: rpcInvalidParams, Message: "Unknown document '" + p.TextDocument.URI + "'."}
+	}
+	if doc.symbols == nil {
+		return p, doc, nil, nil
+	}
+	token := doc.symbols.tokenAt(p.Position.Line+1, doc.column(p.Position))
+	if token == nil {
+		return p, doc, nil, nil
+	}
+	return p, doc, doc.symbols.references[token], nil
+}
+
+func (s *LanguageServer) definition(params json.RawMessage) (interface{}, error) {
+	p, doc, target, err := s.lookup(params)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	return &lspLocation{URI: p.TextDocument.URI, Range: doc.tokenRange(target)}, nil
+}
+
+func (s *LanguageServer) references(params json.RawMessage) (interface{}, error) {
+	p, doc, target, err := s.lookup(params)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	var context struct {
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}
+	err = json.Unmarshal(params, &context)
+	if err != nil {
+		return nil, err
+	}
+	locations := []*lspLocation{}
+	for _, token := range doc.symbols.referencesTo(target) {
+		if token == target && !context.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, &lspLocation{URI: p.TextDocument.URI, Range: doc.tokenRange(token)})
+	}
+	return locations, nil
+}
+
+func (s *LanguageServer) hover(params json.RawMessage) (interface{}, error) {
+	_, doc, target, err := s.lookup(params)
+	if err != nil || target == nil {
+		return nil, err
+	}
+	decl, ok := doc.declarations[target]
+	if !ok {
+		return nil, nil
+	}
+	value := "```lox\n" + decl.detail + "\n```"
+	if decl.doc != "" {
+		value += "\n\n" + decl.doc
+	}
+	return map[string]interface{}{
+		"contents": map[string]interface{}{
+			"kind":  "markdown",
+			"value": value,
+		},
+	}, nil
+}
+
+func (s *LanguageServer) documentSymbol(params json.RawMessage) (interface{}, error) {
+	_, doc, _, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+	return doc.documentSymbols(doc.outline), nil
+}
+
+// completion offers, after a ".", the members of the receiver when the
+// document tells what it is, and otherwise keywords, native functions and the
+// names declared in the document.
+func (s *LanguageServer) completion(params json.RawMessage) (interface{}, error) {
+	p, doc, _, err := s.lookup(params)
+	if err != nil {
+		return nil, err
+	}
+	prefix := doc.textBefore(p.Position)
+	start := identifierStart(prefix, len(prefix))
+	word := prefix[start:]
+
+	var candidates []*lspCompletionItem
+	if start > 0 && prefix[start-1] == '.' {
+		for _, member := range doc.members(prefix[:start-1], p.Position.Line+1, map[*declaration]bool{}) {
+			candidates = append(candidates, member.completionItem())
+		}
+	} else {
+		for keyword := range *NewTokenMap() {
+			candidates = append(candidates, &lspCompletionItem{Label: keyword, Kind: 14})
+		}
+		for name := range NewInterpreter().globals.values {
+			candidates = append(candidates, &lspCompletionItem{Label: name, Kind: 3, Detail: "native function"})
+		}
+		for _, decl := range doc.declarations {
+			if decl.kind != symbolMethod && decl.kind != symbolEnumMember {
+				candidates = append(candidates, decl.completionItem())
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	items := []*lspCompletionItem{}
+	for _, item := range candidates {
+		if strings.HasPrefix(item.Label, word) && !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items, nil
+}
+
+// =====
+
+// analyze scans, parses and resolves the text of a document. The symbols are
+// kept even when the resolver fails, for the part of the program it went through.
+func analyze(text string, utf16 bool) *document {
+	doc := &document{
+		text:         text,
+		lines:        strings.Split(text, "\n"),
+		utf16:        utf16,
+		declarations: map[*Token]*declaration{},
+		diagnostics:  []*lspDiagnostic{},
+	}
+	tokens, err := NewScanner(NewTokenMap(), text).ScanTokens()
+	if err != nil {
+		doc.diagnostics = append(doc.diagnostics, doc.errorDiagnostic(err))
+		return doc
+	}
+	parser := NewParser(&tokens)
+	statements, err := parser.Parse()
+	if err != nil {
+		doc.diagnostics = append(doc.diagnostics, doc.errorDiagnostic(err))
+		return doc
+	}
+	doc.statements = statements
+	doc.info = parser.info
+
+	resolver := NewResolver(NewInterpreter())
+	resolver.symbols = newSymbolIndex()
+	err = resolver.resolveStatements(&statements)
+	if err != nil {
+		doc.diagnostics = append(doc.diagnostics, doc.errorDiagnostic(err))
+	}
+	for _, warning := range resolver.Warnings() {
+		doc.diagnostics = append(doc.diagnostics, &lspDiagnostic{
+			Range:    doc.tokenRange(warning.token),
+			Severity: 2,
+			Source:   "glox",
+			Message:  warning.message,
+		})
+	}
+	resolver.symbols.link()
+	doc.symbols = resolver.symbols
+
+	for _, stmt := range statements {
+		doc.outline = append(doc.outline, doc.declare(stmt)...)
+	}
+	return doc
+}
+
+// keep takes over the analysis of an earlier text of the document.
+func (s *document) keep(previous *document) {
+	s.statements = previous.statements
+	s.info = previous.info
+	s.symbols = previous.symbols
+	s.declarations = previous.declarations
+	s.outline = previous.outline
+}
+
+func (s *document) errorDiagnostic(err error) *lspDiagnostic {
+	diagnostic := &lspDiagnostic{Severity: 1, Source: "glox", Message: err.Error()}
+	switch t := err.(type) {
+	case *LineError:
+		diagnostic.Range = lspRange{
+			Start: lspPosition{Line: t.line - 1, Character: s.character(t.line, t.column)},
+			End:   lspPosition{Line: t.line - 1, Character: s.character(t.line, t.column+1)},
+		}
+		diagnostic.Message = t.message
+	case *ParserError:
+		diagnostic.Range = s.tokenRange(t.token)
+		diagnostic.Message = t.message
+	case *ResolverError:
+		diagnostic.Range = s.tokenRange(t.token)
+		diagnostic.Message = t.message
+	}
+	return diagnostic
+}
+
+// tokenRange is the range of the first line of a token.
+func (s *document) tokenRange(token *Token) lspRange {
+	column := token.column
+	if column < 1 {
+		column = 1
+	}
+	lexeme, _, _ := strings.Cut(token.lexeme, "\n")
+	return lspRange{
+		Start: lspPosition{Line: token.line - 1, Character: s.character(token.line, column)},
+		End:   lspPosition{Line: token.line - 1, Character: s.character(token.line, column+utf8.RuneCountInString(lexeme))},
+	}
+}
+
+// character converts a column of a line, counted in code points from 1, to
+// the character of an LSP position.
+func (s *document) character(line int, column int) int {
+	if !s.utf16 || line < 1 || line > len(s.lines) {
+		return column - 1
+	}
+	character, runes := 0, 0
+	for _, ch := range s.lines[line-1] {
+		if runes == column-1 {
+			break
+		}
+		character += utf16Length(ch)
+		runes++
+	}
+	return character + column - 1 - runes
+}
+
+// column converts the character of an LSP position to a column counted in code points from 1.
+func (s *document) column(position lspPosition) int {
+	if !s.utf16 || position.Line < 0 || position.Line >= len(s.lines) {
+		return position.Character + 1
+	}
+	character, runes := 0, 0
+	for _, ch := range s.lines[position.Line] {
+		if character >= position.Character {
+			break
+		}
+		character += utf16Length(ch)
+		runes++
+	}
+	return runes + 1
+}
+
+// utf16Length is the number of UTF-16 code units encoding a code point.
+func utf16Length(ch rune) int {
+	if ch >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// declare describes the names declared by a statement, looking into nested
+// statements for local ones. It returns the declarations that show up in the
+// outline of the document: functions, classes and the like, with their members.
+func (s *document) declare(stmt Stmt) []*declaration {
+	switch t := stmt.(type) {
+	case *Block:
+		for _, inner := range *t.statements {
+			s.declare(inner)
+		}
+	case *If:
+		s.declare(t.thenBranch)
+		if t.elseBranch != nil {
+			s.declare(t.elseBranch)
+		}
+	case *While:
+		s.declare(t.body)
+	case *Match:
+		for _, matchCase := range *t.cases {
+			for _, pattern := range *matchCase.patterns {
+				for _, variable := range patternVariables(pattern) {
+					s.add(variable.name, symbolVariable, "var "+variable.name.lexeme, "", nil)
+				}
+			}
+			s.declare(matchCase.body)
+		}
+	case *Var:
+		return []*declaration{s.add(t.name, symbolVariable, "var "+t.name.lexeme+annotationString(t.annotation), t.doc, t)}
+	case *VarPattern:
+		for _, variable := range patternVariables(t.pattern) {
+			s.add(variable.name, symbolVariable, "var "+variable.name.lexeme, "", nil)
+		}
+	case *Const:
+		return []*declaration{s.add(t.name, symbolConstant, "const "+t.name.lexeme, "", t)}
+	case *Function:
+		return []*declaration{s.function(t, symbolFunction, "fun ")}
+	case *Class:
+		detail := "class " + t.name.lexeme
+		if t.abstract != nil {
+			detail = "abstract " + detail
+		}
+		if t.superclass != nil {
+			detail += " < " + t.superclass.name.lexeme
+		}
+		if len(*t.interfaces) > 0 {
+			detail += " implements " + variableNames(*t.interfaces)
+		}
+		if len(*t.traits) > 0 {
+			detail += " with " + variableNames(*t.traits)
+		}
+		decl := s.add(t.name, symbolClass, detail, t.doc, t)
+		decl.children = s.methods(*t.methods, t.name.lexeme)
+		return []*declaration{decl}
+	case *Interface:
+		decl := s.add(t.name, symbolInterface, "interface "+t.name.lexeme, "", t)
+		decl.children = s.methods(*t.methods, t.name.lexeme)
+		return []*declaration{decl}
+	case *Trait:
+		decl := s.add(t.name, symbolInterface, "trait "+t.name.lexeme, "", t)
+		decl.children = s.methods(*t.methods, t.name.lexeme)
+		return []*declaration{decl}
+	case *Enum:
+		decl := s.add(t.name, symbolEnum, "enum "+t.name.lexeme, "", t)
+		for _, member := range *t.members {
+			s.symbols.reference(member, member)
+			decl.children = append(decl.children, s.add(member, symbolEnumMember, t.name.lexeme+"."+member.lexeme, "", nil))
+		}
+		return []*declaration{decl}
+	}
+	return nil
+}
+
+// methods declares the methods of a class, an interface or a trait. Calls of
+// methods are dispatched at runtime, so only their declarations are indexed.
+func (s *document) methods(methods []*Function, owner string) []*declaration {
+	var declarations []*declaration
+	for _, method := range methods {
+		s.symbols.reference(method.name, method.name)
+		declarations = append(declarations, s.function(method, symbolMethod, owner+"."))
+	}
+	return declarations
+}
+
+func (s *document) function(stmt *Function, kind int, prefix string) *declaration {
+	var params []string
+	for i, param := range *stmt.params {
+		params = append(params, param.lexeme+annotationString((*stmt.paramTypes)[i]))
+		s.add(param, symbolVariable, "parameter "+param.lexeme+annotationString((*stmt.paramTypes)[i]), "", nil)
+	}
+	if stmt.rest != nil {
+		params = append(params, "..."+stmt.rest.lexeme)
+		s.add(stmt.rest, symbolVariable, "parameter ..."+stmt.rest.lexeme, "", nil)
+	}
+	detail := prefix + stmt.name.lexeme + "(" + strings.Join(params, ", ") + ")" + annotationString(stmt.returnType)
+	decl := s.add(stmt.name, kind, detail, stmt.doc, stmt)
+	if stmt.body != nil {
+		for _, inner := range *stmt.body {
+			s.declare(inner)
+		}
+	}
+	return decl
+}
+
+func (s *document) add(name *Token, kind int, detail string, doc string, stmt Stmt) *declaration {
+	decl := &declaration{name: name, kind: kind, detail: detail, doc: doc, stmt: stmt}
+	s.declarations[name] = decl
+	return decl
+}
+
+func (s *document) documentSymbols(declarations []*declaration) []*lspDocumentSymbol {
+	symbols := []*lspDocumentSymbol{}
+	for _, decl := range declarations {
+		symbol := &lspDocumentSymbol{
+			Name:           decl.name.lexeme,
+			Detail:         decl.detail,
+			Kind:           decl.kind,
+			Range:          s.tokenRange(decl.name),
+			SelectionRange: s.tokenRange(decl.name),
+		}
+		if span, ok := s.info.spans[decl.stmt]; ok {
+			symbol.Range = lspRange{Start: s.tokenRange(span.start).Start, End: s.tokenRange(span.end).End}
+		}
+		if len(decl.children) > 0 {
+			symbol.Children = s.documentSymbols(decl.children)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// members returns the members of the receiver ending the text, as seen from
+// the line: the methods of the class around the line for "this" and "super",
+// the members of a class, an interface, a trait or an enum for its name, and
+// the methods of the class a variable is annotated with or initialized by
+// calling. Methods inherited from superclasses and traits are included.
+func (s *document) members(text string, line int, seen map[*declaration]bool) []*declaration {
+	name := text[identifierStart(text, len(text)):]
+	var owner *declaration
+	switch name {
+	case "this":
+		owner = s.enclosingClass(line)
+	case "super":
+		if class := s.enclosingClass(line); class != nil && class.stmt.(*Class).superclass != nil {
+			owner = s.typeNamed(class.stmt.(*Class).superclass.name.lexeme, line)
+		}
+	default:
+		owner = s.typeNamed(name, line)
+		if owner == nil {
+			owner = s.classOf(name, line)
+		}
+	}
+	if owner == nil || seen[owner] {
+		return nil
+	}
+	seen[owner] = true
+	members := owner.children
+	if class, ok := owner.stmt.(*Class); ok {
+		if class.superclass != nil {
+			members = append(members, s.members(class.superclass.name.lexeme, line, seen)...)
+		}
+		for _, trait := range *class.traits {
+			members = append(members, s.members(trait.name.lexeme, line, seen)...)
+		}
+	}
+	return members
+}
+
+// declared returns the declaration of the name closest above the line.
+func (s *document) declared(name string, line int) *declaration {
+	var found *declaration
+	for token, decl := range s.declarations {
+		if token.lexeme == name && token.line <= line && (found == nil || token.line > found.name.line) {
+			found = decl
+		}
+	}
+	return found
+}
+
+// typeNamed returns the declaration of the class, the interface, the trait
+// or the enum with the name.
+func (s *document) typeNamed(name string, line int) *declaration {
+	decl := s.declared(name, line)
+	if decl == nil || (decl.kind != symbolClass && decl.kind != symbolInterface && decl.kind != symbolEnum) {
+		return nil
+	}
+	return decl
+}
+
+// classOf returns the declaration of the class a variable is annotated with
+// or initialized by calling.
+func (s *document) classOf(name string, line int) *declaration {
+	decl := s.declared(name, line)
+	if decl == nil {
+		return nil
+	}
+	variable, ok := decl.stmt.(*Var)
+	if !ok {
+		return nil
+	}
+	if variable.annotation != nil {
+		return s.typeNamed(variable.annotation.lexeme, line)
+	}
+	if call, ok := variable.initializer.(*Call); ok {
+		if callee, ok := call.callee.(*Variable); ok {
+			return s.typeNamed(callee.name.lexeme, line)
+		}
+	}
+	return nil
+}
+
+// enclosingClass returns the declaration of the class in the outline whose
+// body holds the line.
+func (s *document) enclosingClass(line int) *declaration {
+	for _, decl := range s.outline {
+		span, ok := s.info.spans[decl.stmt]
+		if _, class := decl.stmt.(*Class); class && ok && span.start.line <= line && line <= span.end.line {
+			return decl
+		}
+	}
+	return nil
+}
+
+// textBefore returns the text of the line up to the position.
+func (s *document) textBefore(position lspPosition) string {
+	if position.Line < 0 || position.Line >= len(s.lines) {
+		return ""
+	}
+	line := s.lines[position.Line]
+	offset := 0
+	for i := 1; i < s.column(position) && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return line[:offset]
+}
+
+func (s *declaration) completionItem() *lspCompletionItem {
+	kinds := map[int]int{
+		symbolClass:      7,
+		symbolMethod:     2,
+		symbolEnum:       13,
+		symbolInterface:  8,
+		symbolFunction:   3,
+		symbolVariable:   6,
+		symbolConstant:   21,
+		symbolEnumMember: 20,
+	}
+	return &lspCompletionItem{Label: s.name.lexeme, Kind: kinds[s.kind], Detail: s.detail}
+}
@@ -0,0 +1,421 @@
+package glox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxHistory = 1000
+
+// ErrInterrupted is returned by ReadLine when the line is cancelled with Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Completer returns the offset in the text before the cursor where the word
+// to complete starts, and the words that could replace it.
+type Completer func(prefix string) (int, []string)
+
+// LineEditor reads lines from a terminal with cursor movement, history and
+// completion. When the input isn't a terminal, it reads plain lines.
+type LineEditor struct {
+	input       *bufio.Reader
+	output      io.Writer
+	fd          int
+	history     []string
+	historyPath string
+	completer   Completer
+}
+
+// NewLineEditor makes an editor reading from the input, with the history
+// kept in the file at historyPath when it isn't empty.
+func NewLineEditor(input io.Reader, output io.Writer, historyPath string, completer Completer) *LineEditor {
+	editor := &LineEditor{
+		input:       bufio.NewReader(input),
+		output:      output,
+		fd:          -1,
+		historyPath: historyPath,
+		completer:   completer,
+	}
+	if file, ok := input.(*os.File); ok {
+		editor.fd = int(file.Fd())
+	}
+	editor.loadHistory()
+	return editor
+}
+
+// ReadLine returns the next line without its line terminator. On a
+// terminal, the line is edited in raw mode; otherwise plain lines are read.
+func (s *LineEditor) ReadLine(prompt string) (string, error) {
+	if !isTerminal(s.fd) {
+		return s.readPlainLine(prompt)
+	}
+	restore, err := enableRawMode(s.fd)
+	if err != nil {
+		return s.readPlainLine(prompt)
+	}
+	defer restore()
+	return s.EditLine(prompt)
+}
+
+// EditLine edits a line with the keys read from the input, as typed on a
+// terminal in raw mode, and records it in the history.
+func (s *LineEditor) EditLine(prompt string) (string, error) {
+	line, err := s.edit(prompt)
+	if err == nil {
+		s.addHistory(line)
+	}
+	return line, err
+}
+
+func (s *LineEditor) readPlainLine(prompt string) (string, error) {
+	_, _ = fmt.Fprint(s.output, prompt)
+	line, err := s.input.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// =====
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+
+	// Keys sent as escape sequences are mapped past the Unicode range.
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// edit runs the editing loop of a single line in raw mode.
+func (s *LineEditor) edit(prompt string) (string, error) {
+	var line []rune
+	position := 0
+	historyIndex := len(s.history)
+	pending := ""
+	s.refresh(prompt, line, position)
+	for {
+		key, err := s.readKey()
+		if err != nil {
+			return "", err
+		}
+		if key == keyCtrlR {
+			found, accepted, err := s.reverseSearch(string(line))
+			if err != nil {
+				return "", err
+			}
+			line = []rune(found)
+			position = len(line)
+			if accepted {
+				s.refresh(prompt, line, position)
+				_, _ = fmt.Fprint(s.output, "\n")
+				return found, nil
+			}
+			s.refresh(prompt, line, position)
+			continue
+		}
+		switch key {
+		case keyEnter, '\n':
+			_, _ = fmt.Fprint(s.output, "\n")
+			return string(line), nil
+		case keyCtrlC:
+			_, _ = fmt.Fprint(s.output, "^C\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(line) == 0 {
+				_, _ = fmt.Fprint(s.output, "\n")
+				return "", io.EOF
+			}
+			if position < len(line) {
+				line = append(line[:position], line[position+1:]...)
+			}
+		case keyDelete:
+			if position < len(line) {
+				line = append(line[:position], line[position+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if position > 0 {
+				line = append(line[:position-1], line[position:]...)
+				position--
+			}
+		case keyLeft, keyCtrlB:
+			if position > 0 {
+				position--
+			}
+		case keyRight, keyCtrlF:
+			if position < len(line) {
+				position++
+			}
+		case keyHome, keyCtrlA:
+			position = 0
+		case keyEnd, keyCtrlE:
+			position = len(line)
+		case keyCtrlK:
+			line = line[:position]
+		case keyCtrlU:
+			line = line[position:]
+			position = 0
+		case keyCtrlW:
+			start := position
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line = append(line[:start], line[position:]...)
+			position = start
+		case keyCtrlL:
+			_, _ = fmt.Fprint(s.output, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			if historyIndex > 0 {
+				if historyIndex == len(s.history) {
+					pending = string(line)
+				}
+				historyIndex--
+				line = []rune(s.history[historyIndex])
+				position = len(line)
+			}
+		case keyDown, keyCtrlN:
+			if historyIndex < len(s.history) {
+				historyIndex++
+				if historyIndex == len(s.history) {
+					line = []rune(pending)
+				} else {
+					line = []rune(s.history[historyIndex])
+				}
+				position = len(line)
+			}
+		case keyTab:
+			line, position = s.complete(line, position)
+		default:
+			if key >= ' ' && key <= unicode.MaxRune && key != keyBackspace {
+				line = append(line[:position], append([]rune{key}, line[position:]...)...)
+				position++
+			}
+		}
+		s.refresh(prompt, line, position)
+	}
+}
+
+// readKey reads a key press, decoding the escape sequences of special keys.
+func (s *LineEditor) readKey() (rune, error) {
+	key, _, err := s.input.ReadRune()
+	if err != nil || key != keyEscape {
+		return key, err
+	}
+	next, _, err := s.input.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	parameter := ""
+	for {
+		ch, _, err := s.input.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if ch >= '0' && ch <= '9' || ch == ';' {
+			parameter += string(ch)
+			continue
+		}
+		switch ch {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		case 'H':
+			return keyHome, nil
+		case 'F':
+			return keyEnd, nil
+		case '~':
+			switch parameter {
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			case "3":
+				return keyDelete, nil
+			}
+		}
+		return keyUnknown, nil
+	}
+}
+
+// refresh redraws the prompt and the line, then moves the cursor to its position.
+func (s *LineEditor) refresh(prompt string, line []rune, position int) {
+	column := utf8.RuneCountInString(prompt) + position
+	_, _ = fmt.Fprintf(s.output, "\r%s%s\x1b[K\r", prompt, string(line))
+	if column > 0 {
+		_, _ = fmt.Fprintf(s.output, "\x1b[%dC", column)
+	}
+}
+
+// reverseSearch runs the Ctrl-R search through the history. It returns the
+// line found, and whether Enter accepted it.
+func (s *LineEditor) reverseSearch(line string) (string, bool, error) {
+	query := ""
+	index := len(s.history)
+	found := line
+	for {
+		_, _ = fmt.Fprintf(s.output, "\r(reverse-i-search)`%s': %s\x1b[K", query, found)
+		key, err := s.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyCtrlR:
+			if i := s.searchHistory(query, index-1); i >= 0 {
+				index = i
+				found = s.history[i]
+			}
+		case keyBackspace, keyCtrlH:
+			if query != "" {
+				_, size := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-size]
+				index = len(s.history)
+				found = line
+				if i := s.searchHistory(query, index-1); query != "" && i >= 0 {
+					index = i
+					found = s.history[i]
+				}
+			}
+		case keyEnter, '\n':
+			return found, true, nil
+		case keyCtrlG, keyCtrlC:
+			_, _ = fmt.Fprint(s.output, "\r\x1b[K")
+			return line, false, nil
+		default:
+			if key < ' ' || key > unicode.MaxRune {
+				_, _ = fmt.Fprint(s.output, "\r\x1b[K")
+				return found, false, nil
+			}
+			query += string(key)
+			if i := s.searchHistory(query, index); i >= 0 {
+				index = i
+				found = s.history[i]
+			}
+		}
+	}
+}
+
+// searchHistory returns the index of the latest entry containing query,
+// looking backwards from the entry at index start, or -1.
+func (s *LineEditor) searchHistory(query string, start int) int {
+	if start >= len(s.history) {
+		start = len(s.history) - 1
+	}
+	for i := start; i >= 0; i-- {
+		if strings.Contains(s.history[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// complete extends the word before the cursor with the longest prefix
+// common to its completions, and lists them when it can't be extended.
+func (s *LineEditor) complete(line []rune, position int) ([]rune, int) {
+	if s.completer == nil {
+		return line, position
+	}
+	prefix := string(line[:position])
+	start, candidates := s.completer(prefix)
+	if len(candidates) == 0 {
+		_, _ = fmt.Fprint(s.output, "\a")
+		return line, position
+	}
+	word := prefix[start:]
+	common := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, common) {
+			_, size := utf8.DecodeLastRuneInString(common)
+			common = common[:len(common)-size]
+		}
+	}
+	if len(common) > len(word) {
+		insertion := []rune(common[len(word):])
+		line = append(line[:position], append(insertion, line[position:]...)...)
+		return line, position + len(insertion)
+	}
+	if len(candidates) > 1 {
+		_, _ = fmt.Fprintf(s.output, "\n%s\n", strings.Join(candidates, "  "))
+	}
+	return line, position
+}
+
+// =====
+
+func (s *LineEditor) loadHistory() {
+	if s.historyPath == "" {
+		return
+	}
+	data, err := os.ReadFile(s.historyPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			s.history = append(s.history, line)
+		}
+	}
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+		_ = os.WriteFile(s.historyPath, []byte(strings.Join(s.history, "\n")+"\n"), 0600)
+	}
+}
+
+// addHistory records a line, unless it is blank or repeats the latest entry,
+// and appends it to the history file.
+func (s *LineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || len(s.history) > 0 && s.history[len(s.history)-1] == line {
+		return
+	}
+	s.history = append(s.history, line)
+	if len(s.history) > maxHistory {
+		s.history = s.history[1:]
+	}
+	if s.historyPath == "" {
+		return
+	}
+	file, err := os.OpenFile(s.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = fmt.Fprintln(file, line)
+}
@@ -0,0 +1,548 @@
+package glox
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// The rules checked by the Linter.
+const (
+	RuleUnusedVariable    = "unused-variable"
+	RuleUnusedParameter   = "unused-parameter"
+	RuleShadow            = "shadow"
+	RuleUnreachable       = "unreachable"
+	RuleSelfAssign        = "self-assign"
+	RuleConstantCondition = "constant-condition"
+	RuleInitCall          = "init-call"
+)
+
+func LintRules() []string {
+	return []string{
+		RuleUnusedVariable,
+		RuleUnusedParameter,
+		RuleShadow,
+		RuleUnreachable,
+		RuleSelfAssign,
+		RuleConstantCondition,
+		RuleInitCall,
+	}
+}
+
+// Linter warns about code that runs but is likely a mistake. A warning is
+// suppressed by a "// lox-ignore rule..." comment on its line or on the
+// line before; without rule names, the comment suppresses every rule.
+// Names starting with "_" are never reported as unused.
+type Linter struct {
+	disabled map[string]bool
+	info     *sourceInfo
+	scopes   []map[string]*lintBinding
+	current  *Token
+	warnings []*LintWarning
+}
+
+// lintBinding is a declared name, with the rule reporting it when it is
+// never read, or an empty rule for names that aren't checked.
+type lintBinding struct {
+	name *Token
+	kind string
+	rule string
+	used bool
+}
+
+func NewLinter() *Linter {
+	return &Linter{
+		disabled: map[string]bool{},
+	}
+}
+
+// Disable turns off a rule.
+func (s *Linter) Disable(rule string) error {
+	for _, known := range LintRules() {
+		if rule == known {
+			s.disabled[rule] = true
+			return nil
+		}
+	}
+	return errors.New("Unknown lint rule '" + rule + "'.")
+}
+
+// Lint checks the source, which must be a valid program, and returns the
+// warnings sorted by position.
+func (s *Linter) Lint(source string) ([]*LintWarning, error) {
+	scanner := NewScanner(NewTokenMap(), source)
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		return nil, err
+	}
+	parser := NewParser(&tokens)
+	statements, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	err = NewResolver(NewInterpreter()).resolveStatements(&statements)
+	if err != nil {
+		return nil, err
+	}
+
+	s.info = parser.info
+	s.scopes = []map[string]*lintBinding{{}}
+	s.warnings = nil
+	s.statements(statements)
+
+	ignored := ignoredRules(source, scanner.comments)
+	var warnings []*LintWarning
+	for _, warning := range s.warnings {
+		if s.disabled[warning.rule] || ignored[warning.token.line][warning.rule] || ignored[warning.token.line][""] {
+			continue
+		}
+		warnings = append(warnings, warning)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].token, warnings[j].token
+		return a.line < b.line || a.line == b.line && a.column < b.column
+	})
+	return warnings, nil
+}
+
+// ignoredRules maps lines to the rules suppressed on them, the empty rule
+// standing for all rules. A comment after code covers its own line, and a
+// comment alone on its line covers the next one.
+func ignoredRules(source string, comments []*comment) map[int]map[string]bool {
+	lines := strings.Split(source, "\n")
+	ignored := map[int]map[string]bool{}
+	for _, c := range comments {
+		fields := strings.FieldsFunc(strings.TrimPrefix(c.text, "//"), func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if !strings.HasPrefix(c.text, "//") || len(fields) == 0 || fields[0] != "lox-ignore" {
+			continue
+		}
+		rules := fields[1:]
+		if len(rules) == 0 {
+			rules = []string{""}
+		}
+		line := c.line
+		if strings.HasPrefix(strings.TrimSpace(lines[c.line-1]), "//") {
+			line++
+		}
+		if ignored[line] == nil {
+			ignored[line] = map[string]bool{}
+		}
+		for _, rule := range rules {
+			ignored[line][rule] = true
+		}
+	}
+	return ignored
+}
+
+func (s *Linter) warn(rule string, token *Token, message string) {
+	s.warnings = append(s.warnings, NewLintWarning(rule, token, message))
+}
+
+func (s *Linter) beginScope() {
+	s.scopes = append(s.scopes, map[string]*lintBinding{})
+}
+
+func (s *Linter) endScope() {
+	for _, binding := range s.scopes[len(s.scopes)-1] {
+		if binding.used || binding.rule == "" {
+			continue
+		}
+		if binding.rule == RuleUnusedParameter {
+			s.warn(binding.rule, binding.name, "Unused parameter '"+binding.name.lexeme+"'.")
+		} else {
+			s.warn(binding.rule, binding.name, "Unused local "+binding.kind+" '"+binding.name.lexeme+"'.")
+		}
+	}
+	s.scopes = s.scopes[:len(s.scopes)-1]
+}
+
+// declare adds a name to the current scope. Names declared at the top level
+// aren't reported as unused, since other code may use them.
+func (s *Linter) declare(name *Token, kind string) {
+	rule := RuleUnusedVariable
+	if kind == "parameter" {
+		rule = RuleUnusedParameter
+	}
+	if len(s.scopes) == 1 || strings.HasPrefix(name.lexeme, "_") {
+		rule = ""
+	}
+	for i := len(s.scopes) - 2; i >= 0; i-- {
+		if outer, ok := s.scopes[i][name.lexeme]; ok && outer.name != nil {
+			s.warn(RuleShadow, name, "'"+name.lexeme+"' shadows the "+outer.kind+" declared at "+outer.name.position()+".")
+			break
+		}
+	}
+	s.scopes[len(s.scopes)-1][name.lexeme] = &lintBinding{name: name, kind: kind, rule: rule}
+}
+
+// use marks the innermost binding of a name as read.
+func (s *Linter) use(name string) {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if binding, ok := s.scopes[i][name]; ok {
+			binding.used = true
+			return
+		}
+	}
+}
+
+// statements checks a list of statements, reporting the first one written
+// after a statement that always returns.
+func (s *Linter) statements(statements []Stmt) {
+	for i, stmt := range statements {
+		s.statement(stmt)
+		if !alwaysReturns(stmt) || i+1 >= len(statements) {
+			continue
+		}
+		// Statements added by desugaring, like the increment of a for loop, have no span.
+		if span, ok := s.info.spans[statements[i+1]]; ok {
+			s.warn(RuleUnreachable, span.start, "Unreachable code.")
+			return
+		}
+	}
+}
+
+func (s *Linter) statement(stmt Stmt) {
+	enclosing := s.current
+	if span, ok := s.info.spans[stmt]; ok {
+		s.current = span.start
+	}
+	_, _ = stmt.accept(s)
+	s.current = enclosing
+}
+
+func (s *Linter) expression(expr Expr) {
+	if expr != nil {
+		_, _ = expr.accept(s)
+	}
+}
+
+func (s *Linter) function(function *Function) {
+	if function.body == nil {
+		return
+	}
+	s.beginScope()
+	for _, param := range *function.params {
+		s.declare(param, "parameter")
+	}
+	if function.rest != nil {
+		s.declare(function.rest, "parameter")
+	}
+	s.statements(*function.body)
+	s.endScope()
+}
+
+// methods checks methods in a scope where "this" is declared.
+func (s *Linter) methods(methods []*Function) {
+	s.beginScope()
+	s.scopes[len(s.scopes)-1]["this"] = &lintBinding{}
+	s.scopes[len(s.scopes)-1]["super"] = &lintBinding{}
+	for _, method := range methods {
+		s.function(method)
+	}
+	s.endScope()
+}
+
+// condition warns when the condition of a statement is a literal. A
+// "while (true)" loop is allowed, since it is the usual way to loop forever.
+func (s *Linter) condition(condition Expr, allowTrue bool) {
+	expr := condition
+	for {
+		grouping, ok := expr.(*Grouping)
+		if !ok {
+			break
+		}
+		expr = grouping.expression
+	}
+	if literal, ok := expr.(*Literal); ok && s.current != nil {
+		truthy := literal.value != nil && literal.value != false
+		if !truthy {
+			s.warn(RuleConstantCondition, s.current, "Condition is always false.")
+		} else if !allowTrue {
+			s.warn(RuleConstantCondition, s.current, "Condition is always true.")
+		}
+	}
+	s.expression(condition)
+}
+
+// alwaysReturns reports whether every path through a statement returns.
+func alwaysReturns(stmt Stmt) bool {
+	switch t := stmt.(type) {
+	case *Return:
+		return true
+	case *Block:
+		for _, inner := range *t.statements {
+			if alwaysReturns(inner) {
+				return true
+			}
+		}
+	case *If:
+		return t.elseBranch != nil && alwaysReturns(t.thenBranch) && alwaysReturns(t.elseBranch)
+	}
+	return false
+}
+
+// sameObject reports whether two expressions surely denote the same object.
+func sameObject(a Expr, b Expr) bool {
+	switch t := a.(type) {
+	case *This:
+		_, ok := b.(*This)
+		return ok
+	case *Variable:
+		v, ok := b.(*Variable)
+		return ok && v.name.lexeme == t.name.lexeme
+	}
+	return false
+}
+
+// =====
+
+func (s *Linter) visitAssignExpr(expr *Assign) (interface{}, error) {
+	if v, ok := expr.value.(*Variable); ok && v.name.lexeme == expr.name.lexeme {
+		s.warn(RuleSelfAssign, expr.name, "'"+expr.name.lexeme+"' is assigned to itself.")
+	}
+	s.expression(expr.value)
+	return nil, nil
+}
+
+// visitAssignPatternExpr doesn't count the assigned variables as read.
+func (s *Linter) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	s.expression(expr.value)
+	var targets func(pattern Expr)
+	targets = func(pattern Expr) {
+		switch p := pattern.(type) {
+		case *Get:
+			s.expression(p.object)
+		case *ListLiteral:
+			for _, element := range *p.elements {
+				targets(element)
+			}
+		}
+	}
+	targets(expr.pattern)
+	return nil, nil
+}
+
+func (s *Linter) visitBinaryExpr(expr *Binary) (interface{}, error) {
+	s.expression(expr.left)
+	s.expression(expr.right)
+	return nil, nil
+}
+
+func (s *Linter) visitCallExpr(expr *Call) (interface{}, error) {
+	if get, ok := expr.callee.(*Get); ok && get.name.lexeme == "init" {
+		s.warn(RuleInitCall, get.name, "Calling 'init' directly runs the initializer again on an existing instance.")
+	}
+	s.expression(expr.callee)
+	for _, argument := range *expr.arguments {
+		s.expression(argument)
+	}
+	return nil, nil
+}
+
+func (s *Linter) visitGetExpr(expr *Get) (interface{}, error) {
+	s.expression(expr.object)
+	return nil, nil
+}
+
+func (s *Linter) visitGroupingExpr(expr *Grouping) (interface{}, error) {
+	s.expression(expr.expression)
+	return nil, nil
+}
+
+func (s *Linter) visitListLiteralExpr(expr *ListLiteral) (interface{}, error) {
+	for _, element := range *expr.elements {
+		s.expression(element)
+	}
+	return nil, nil
+}
+
+func (s *Linter) visitLiteralExpr(_ *Literal) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Linter) visitLogicalExpr(expr *Logical) (interface{}, error) {
+	s.expression(expr.left)
+	s.expression(expr.right)
+	return nil, nil
+}
+
+func (s *Linter) visitMapLiteralExpr(expr *MapLiteral) (interface{}, error) {
+	for i, key := range *expr.keys {
+		s.expression(key)
+		s.expression((*expr.values)[i])
+	}
+	return nil, nil
+}
+
+func (s *Linter) visitObjectPatternExpr(_ *ObjectPattern) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Linter) visitSetExpr(expr *Set) (interface{}, error) {
+	if get, ok := expr.value.(*Get); ok && get.name.lexeme == expr.name.lexeme && sameObject(get.object, expr.object) {
+		s.warn(RuleSelfAssign, expr.name, "Property '"+expr.name.lexeme+"' is assigned to itself.")
+	}
+	s.expression(expr.object)
+	s.expression(expr.value)
+	return nil, nil
+}
+
+func (s *Linter) visitSpreadExpr(expr *Spread) (interface{}, error) {
+	s.expression(expr.expression)
+	return nil, nil
+}
+
+func (s *Linter) visitSuperExpr(_ *Super) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Linter) visitThisExpr(_ *This) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Linter) visitUnaryExpr(expr *Unary) (interface{}, error) {
+	s.expression(expr.right)
+	return nil, nil
+}
+
+func (s *Linter) visitVariableExpr(expr *Variable) (interface{}, error) {
+	s.use(expr.name.lexeme)
+	return nil, nil
+}
+
+func (s *Linter) visitBlockStmt(stmt *Block) (interface{}, error) {
+	s.beginScope()
+	s.statements(*stmt.statements)
+	s.endScope()
+	return nil, nil
+}
+
+func (s *Linter) visitClassStmt(stmt *Class) (interface{}, error) {
+	s.declare(stmt.name, "class")
+	if stmt.superclass != nil {
+		s.expression(stmt.superclass)
+	}
+	for _, iface := range *stmt.interfaces {
+		s.expression(iface)
+	}
+	for _, trait := range *stmt.traits {
+		s.expression(trait)
+	}
+	s.methods(*stmt.methods)
+	return nil, nil
+}
+
+func (s *Linter) visitConstStmt(stmt *Const) (interface{}, error) {
+	s.expression(stmt.initializer)
+	s.declare(stmt.name, "constant")
+	return nil, nil
+}
+
+func (s *Linter) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	s.declare(stmt.name, "enum")
+	return nil, nil
+}
+
+func (s *Linter) visitExpressionStmt(stmt *Expression) (interface{}, error) {
+	s.expression(stmt.expression)
+	return nil, nil
+}
+
+func (s *Linter) visitFunctionStmt(stmt *Function) (interface{}, error) {
+	s.declare(stmt.name, "function")
+	s.function(stmt)
+	return nil, nil
+}
+
+func (s *Linter) visitIfStmt(stmt *If) (interface{}, error) {
+	s.condition(stmt.condition, false)
+	s.statement(stmt.thenBranch)
+	if stmt.elseBranch != nil {
+		s.statement(stmt.elseBranch)
+	}
+	return nil, nil
+}
+
+func (s *Linter) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	s.declare(stmt.name, "interface")
+	return nil, nil
+}
+
+func (s *Linter) visitMatchStmt(stmt *Match) (interface{}, error) {
+	s.expression(stmt.subject)
+	for _, matchCase := range *stmt.cases {
+		s.beginScope()
+		for _, pattern := range *matchCase.patterns {
+			s.pattern(pattern)
+		}
+		s.statement(matchCase.body)
+		s.endScope()
+	}
+	return nil, nil
+}
+
+// pattern declares the variables bound by a pattern, and reads the classes
+// and enum members it refers to.
+func (s *Linter) pattern(pattern Expr) {
+	switch p := pattern.(type) {
+	case *Variable:
+		if !isWildcard(p) {
+			s.declare(p.name, "variable")
+		}
+	case *Get:
+		s.expression(p)
+	case *Call:
+		s.expression(p.callee)
+		for _, argument := range *p.arguments {
+			s.pattern(argument)
+		}
+	case *ListLiteral:
+		for _, element := range *p.elements {
+			s.pattern(element)
+		}
+	case *ObjectPattern:
+		for _, value := range *p.values {
+			s.pattern(value)
+		}
+	case *Spread:
+		s.pattern(p.expression)
+	}
+}
+
+func (s *Linter) visitPrintStmt(stmt *Print) (interface{}, error) {
+	s.expression(stmt.expression)
+	return nil, nil
+}
+
+func (s *Linter) visitReturnStmt(stmt *Return) (interface{}, error) {
+	s.expression(stmt.value)
+	return nil, nil
+}
+
+func (s *Linter) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	s.declare(stmt.name, "trait")
+	s.methods(*stmt.methods)
+	return nil, nil
+}
+
+func (s *Linter) visitVarStmt(stmt *Var) (interface{}, error) {
+	s.expression(stmt.initializer)
+	s.declare(stmt.name, "variable")
+	return nil, nil
+}
+
+func (s *Linter) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	s.expression(stmt.initializer)
+	for _, variable := range patternVariables(stmt.pattern) {
+		s.declare(variable.name, "variable")
+	}
+	return nil, nil
+}
+
+func (s *Linter) visitWhileStmt(stmt *While) (interface{}, error) {
+	s.condition(stmt.condition, true)
+	s.statement(stmt.body)
+	return nil, nil
+}
@@ -1,19 +1,29 @@
 package glox
 
 type LoxClass struct {
-	name       string
-	superclass *LoxClass
-	methods    *map[string]*LoxFunction
+	name           string
+	declaration    *Class
+	superclass     *LoxClass
+	interfaces     []*LoxInterface
+	methods        *map[string]*LoxFunction
+	privateMethods *map[string]*LoxFunction
 }
 
-func NewLoxClass(name string, superclass *LoxClass, methods *map[string]*LoxFunction) *LoxClass {
+func NewLoxClass(declaration *Class, superclass *LoxClass, interfaces []*LoxInterface, methods *map[string]*LoxFunction, privateMethods *map[string]*LoxFunction) *LoxClass {
 	return &LoxClass{
-		name:       name,
-		superclass: superclass,
-		methods:    methods,
+		name:           declaration.name.lexeme,
+		declaration:    declaration,
+		superclass:     superclass,
+		interfaces:     interfaces,
+		methods:        methods,
+		privateMethods: privateMethods,
 	}
 }
 
+func (s *LoxClass) isAbstract() bool {
+	return s.declaration.abstract != nil
+}
+
 func (s *LoxClass) arity() int {
 	initializer := s.findMethod("init")
 	if initializer == nil {
@@ -22,7 +32,15 @@ func (s *LoxClass) arity() int {
 	return initializer.arity()
 }
 
+func (s *LoxClass) isVariadic() bool {
+	initializer := s.findMethod("init")
+	return initializer != nil && initializer.isVariadic()
+}
+
 func (s *LoxClass) call(interpreter *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	if s.isAbstract() {
+		return nil, NewNativeError("Can't instantiate abstract class '" + s.name + "'.")
+	}
 	instance := NewLoxInstance(s)
 	initializer := s.findMethod("init")
 	if initializer != nil {
@@ -48,6 +66,66 @@ func (s *LoxClass) findMethod(name string) *LoxFunction {
 	return nil
 }
 
+// checkContract verifies that the class provides every abstract and
+// interface method required along its superclass chain, with matching
+// parameters. Abstract classes may leave requirements to their subclasses.
+func (s *LoxClass) checkContract() error {
+	for c := s; c != nil; c = c.superclass {
+		for _, method := range *c.declaration.methods {
+			if method.body == nil {
+				err := s.checkRequirement(method, c.name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, iface := range c.interfaces {
+			for _, method := range *iface.methods {
+				err := s.checkRequirement(method, iface.name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *LoxClass) checkRequirement(required *Function, owner string) error {
+	name := required.name.lexeme
+	method := s.findMethod(name)
+	if method == nil {
+		if s.isAbstract() {
+			return nil
+		}
+		return NewRuntimeError(s.declaration.name, "Class '"+s.name+"' must implement method '"+name+"' from '"+owner+"'.")
+	}
+	if method.arity() != len(*required.params) || method.isVariadic() != (required.rest != nil) {
+		return NewRuntimeError(method.declaration.name, "Method '"+name+"' in class '"+s.name+"' doesn't match the parameters declared in '"+owner+"'.")
+	}
+	return nil
+}
+
+func (s *LoxClass) isSubclassOf(class *LoxClass) bool {
+	for c := s; c != nil; c = c.superclass {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *LoxClass) implements(iface *LoxInterface) bool {
+	for c := s; c != nil; c = c.superclass {
+		for _, i := range c.interfaces {
+			if i == iface {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *LoxClass) String() string {
 	return s.name
 }
@@ -0,0 +1,86 @@
+package glox
+
+type LoxEnum struct {
+	name    string
+	members []*LoxEnumMember
+}
+
+func NewLoxEnum(name string, memberNames []string) *LoxEnum {
+	enum := &LoxEnum{
+		name: name,
+	}
+	for ordinal, memberName := range memberNames {
+		enum.members = append(enum.members, &LoxEnumMember{
+			enum:    enum,
+			name:    memberName,
+			ordinal: ordinal,
+		})
+	}
+	return enum
+}
+
+func (s *LoxEnum) get(name *Token) (interface{}, error) {
+	for _, member := range s.members {
+		if member.name == name.lexeme {
+			return member, nil
+		}
+	}
+	if name.lexeme == "values" {
+		return NewEnumValuesLoxFunction(s), nil
+	}
+	return nil, NewRuntimeError(name, "Undefined enum member '"+name.lexeme+"'.")
+}
+
+func (s *LoxEnum) String() string {
+	return s.name
+}
+
+// =====
+
+type LoxEnumMember struct {
+	enum    *LoxEnum
+	name    string
+	ordinal int
+}
+
+func (s *LoxEnumMember) get(name *Token) (interface{}, error) {
+	switch name.lexeme {
+	case "name":
+		return s.name, nil
+	case "ordinal":
+		return int64(s.ordinal), nil
+	}
+	return nil, NewRuntimeError(name, "Undefined property '"+name.lexeme+"'.")
+}
+
+func (s *LoxEnumMember) String() string {
+	return s.enum.name + "." + s.name
+}
+
+// =====
+
+type enumValuesLoxFunction struct {
+	enum *LoxEnum
+}
+
+func NewEnumValuesLoxFunction(enum *LoxEnum) *enumValuesLoxFunction {
+	return &enumValuesLoxFunction{
+		enum: enum,
+	}
+}
+
+func (s *enumValuesLoxFunction) arity() int {
+	return 0
+}
+
+func (s *enumValuesLoxFunction) call(_ *Interpreter, _ *[]interface{}) (interface{}, error) {
+	var values []interface{}
+	for _, member := range s.enum.members {
+		values = append(values, member)
+	}
+	return NewLoxList(values), nil
+}
+
+func (s *enumValuesLoxFunction) String() string {
+	return "<Function " + s.enum.name + ".values>"
+}
@@ -9,16 +9,18 @@ import "fmt"
 
 type LineError struct {
 	line    int
+	column  int
 	message string
 }
 
 func (s *LineError) Error() string {
-	return fmt.Sprintf("[line %v] Error: %v\n", s.line, s.message)
+	return fmt.Sprintf("[line %v, column %v] Error: %v\n", s.line, s.column, s.message)
 }
 
-func NewLineError(line int, message string) *LineError {
+func NewLineError(line int, column int, message string) *LineError {
 	return &LineError{
 		line:    line,
+		column:  column,
 		message: message,
 	}
 }
@@ -35,8 +37,8 @@ func (s *ParserError) Error() string {
 	if s.token.tokenType != TokenEof {
 		where = "\"" + s.token.String() + "\""
 	}
-	return fmt.Sprintf("[line %v] Error at %v: %v\n",
-		s.token.line, where, s.message)
+	return fmt.Sprintf("[%v] Error at %v: %v\n",
+		s.token.position(), where, s.message)
 }
 
 func NewParserError(token *Token, message string) *ParserError {
@@ -54,8 +56,8 @@ type RuntimeError struct {
 }
 
 func (s *RuntimeError) Error() string {
-	return fmt.Sprintf("[line %v] RuntimeError at \"%v\": %v",
-		s.token.line, s.token.String(), s.message)
+	return fmt.Sprintf("[%v] RuntimeError at \"%v\": %v",
+		s.token.position(), s.token.String(), s.message)
 }
 
 func NewRuntimeError(token *Token, message string) *RuntimeError {
@@ -67,6 +69,24 @@ func NewRuntimeError(token *Token, message string) *RuntimeError {
 
 // =====
 
+// NativeError is returned by native functions, which have no token to report.
+// The interpreter turns it into a RuntimeError at the call site.
+type NativeError struct {
+	message string
+}
+
+func (s *NativeError) Error() string {
+	return s.message
+}
+
+func NewNativeError(message string) *NativeError {
+	return &NativeError{
+		message: message,
+	}
+}
+
+// =====
+
 type ResolverError struct {
 	token   *Token
 	message string
@@ -77,8 +97,8 @@ func (s *ResolverError) Error() string {
 	if s.token.tokenType != TokenEof {
 		where = "\"" + s.token.String() + "\""
 	}
-	return fmt.Sprintf("[line %v] Error at %v: %v\n",
-		s.token.line, where, s.message)
+	return fmt.Sprintf("[%v] Error at %v: %v\n",
+		s.token.position(), where, s.message)
 }
 
 func NewResolverError(token *Token, message string) *ResolverError {
@@ -87,3 +107,66 @@ func NewResolverError(token *Token, message string) *ResolverError {
 		message: message,
 	}
 }
+
+// =====
+
+type ResolverWarning struct {
+	token   *Token
+	message string
+}
+
+func (s *ResolverWarning) String() string {
+	return fmt.Sprintf("[%v] Warning at \"%v\": %v",
+		s.token.position(), s.token.String(), s.message)
+}
+
+func NewResolverWarning(token *Token, message string) *ResolverWarning {
+	return &ResolverWarning{
+		token:   token,
+		message: message,
+	}
+}
+
+// =====
+
+// LintWarning is reported by the Linter. Unlike errors, warnings don't stop a program from running.
+type LintWarning struct {
+	rule    string
+	token   *Token
+	message string
+}
+
+func (s *LintWarning) String() string {
+	return fmt.Sprintf("[%v] Warning (%v): %v", s.token.position(), s.rule, s.message)
+}
+
+func (s *LintWarning) Rule() string {
+	return s.rule
+}
+
+func NewLintWarning(rule string, token *Token, message string) *LintWarning {
+	return &LintWarning{
+		rule:    rule,
+		token:   token,
+		message: message,
+	}
+}
+
+// =====
+
+type TypeError struct {
+	token   *Token
+	message string
+}
+
+func (s *TypeError) Error() string {
+	return fmt.Sprintf("[%v] Error at \"%v\": %v\n",
+		s.token.position(), s.token.String(), s.message)
+}
+
+func NewTypeError(token *Token, message string) *TypeError {
+	return &TypeError{
+		token:   token,
+		message: message,
+	}
+}
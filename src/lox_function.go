@@ -7,6 +7,18 @@ type LoxCallable interface {
 	call(interpreter *Interpreter, arguments *[]interface{}) (interface{}, error)
 }
 
+// variadicCallable is implemented by callables accepting more arguments than their arity.
+type variadicCallable interface {
+	isVariadic() bool
+}
+
+func checkArity(callable LoxCallable, count int) bool {
+	if v, ok := callable.(variadicCallable); ok && v.isVariadic() {
+		return count >= callable.arity()
+	}
+	return count == callable.arity()
+}
+
 // =====
 
 type LoxFunction struct {
@@ -27,6 +39,10 @@ func (s *LoxFunction) arity() int {
 	return len(*s.declaration.params)
 }
 
+func (s *LoxFunction) isVariadic() bool {
+	return s.declaration.rest != nil
+}
+
 func (s *LoxFunction) call(interpreter *Interpreter, arguments *[]interface{}) (interface{}, error) {
 	environment := NewEnvironment(s.closure)
 	for i, param := range *s.declaration.params {
@@ -35,6 +51,17 @@ func (s *LoxFunction) call(interpreter *Interpreter, arguments *[]interface{}) (
 			return nil, err
 		}
 	}
+	if s.declaration.rest != nil {
+		rest := append([]interface{}{}, (*arguments)[s.arity():]...)
+		err := environment.define(s.declaration.rest.lexeme, NewLoxList(rest))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if interpreter.tracer != nil {
+		interpreter.tracer.enterFunction(s, environment)
+		defer interpreter.tracer.leaveFunction()
+	}
 	err := interpreter.executeBlock(s.declaration.body, environment)
 	if returnValue, ok := err.(*ReturnPseudoError); ok {
 		if s.isInitializer {
@@ -80,3 +107,107 @@ func (s *clockLoxFunction) call(_ *Interpreter, _ *[]interface{}) (interface{},
 func (s *clockLoxFunction) String() string {
 	return "<Function clock>"
 }
+
+// =====
+
+type freezeLoxFunction struct{}
+
+func NewFreezeLoxFunction() *freezeLoxFunction {
+	return &freezeLoxFunction{}
+}
+
+func (s *freezeLoxFunction) arity() int {
+	return 1
+}
+
+func (s *freezeLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, ok := (*arguments)[0].(*LoxInstance)
+	if !ok {
+		return nil, NewNativeError("Only instances can be frozen.")
+	}
+	instance.freeze()
+	return instance, nil
+}
+
+func (s *freezeLoxFunction) String() string {
+	return "<Function freeze>"
+}
+
+// =====
+
+type setDecimalPrecisionLoxFunction struct{}
+
+func NewSetDecimalPrecisionLoxFunction() *setDecimalPrecisionLoxFunction {
+	return &setDecimalPrecisionLoxFunction{}
+}
+
+func (s *setDecimalPrecisionLoxFunction) arity() int {
+	return 1
+}
+
+func (s *setDecimalPrecisionLoxFunction) call(interpreter *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	digits, ok := (*arguments)[0].(int64)
+	if !ok || digits < 0 {
+		return nil, NewNativeError("Decimal precision must be a non-negative integer.")
+	}
+	interpreter.decimals.precision = int(digits)
+	return nil, nil
+}
+
+func (s *setDecimalPrecisionLoxFunction) String() string {
+	return "<Function setDecimalPrecision>"
+}
+
+// =====
+
+type setDecimalRoundingLoxFunction struct{}
+
+func NewSetDecimalRoundingLoxFunction() *setDecimalRoundingLoxFunction {
+	return &setDecimalRoundingLoxFunction{}
+}
+
+func (s *setDecimalRoundingLoxFunction) arity() int {
+	return 1
+}
+
+func (s *setDecimalRoundingLoxFunction) call(interpreter *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	name, _ := (*arguments)[0].(string)
+	mode, ok := roundingModes[name]
+	if !ok {
+		return nil, NewNativeError("Unknown rounding mode. Expect one of \"half_even\", \"half_up\", " +
+			"\"half_down\", \"up\", \"down\", \"ceiling\" or \"floor\".")
+	}
+	interpreter.decimals.rounding = mode
+	return nil, nil
+}
+
+func (s *setDecimalRoundingLoxFunction) String() string {
+	return "<Function setDecimalRounding>"
+}
+
+// =====
+
+type divLoxFunction struct{}
+
+func NewDivLoxFunction() *divLoxFunction {
+	return &divLoxFunction{}
+}
+
+func (s *divLoxFunction) arity() int {
+	return 2
+}
+
+func (s *divLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	left, right := (*arguments)[0], (*arguments)[1]
+	if !isInteger(left) || !isInteger(right) {
+		return nil, NewNativeError("Arguments to 'div' must be integers.")
+	}
+	if toBigInt(right).Sign() == 0 {
+		return nil, NewNativeError("Division by zero.")
+	}
+	return integerQuotient(left, right), nil
+}
+
+func (s *divLoxFunction) String() string {
+	return "<Function div>"
+}
@@ -1,14 +1,24 @@
 package glox
 
+// privateKey identifies a private field by the class that declared it, so
+// a subclass can use the same private name without clashing.
+type privateKey struct {
+	owner *LoxClass
+	name  string
+}
+
 type LoxInstance struct {
-	class  *LoxClass
-	fields *map[string]interface{}
+	class    *LoxClass
+	fields   *map[string]interface{}
+	privates map[privateKey]interface{}
+	frozen   bool
 }
 
 func NewLoxInstance(class *LoxClass) *LoxInstance {
 	return &LoxInstance{
-		class:  class,
-		fields: &map[string]interface{}{},
+		class:    class,
+		fields:   &map[string]interface{}{},
+		privates: map[privateKey]interface{}{},
 	}
 }
 
@@ -23,8 +33,34 @@ func (s *LoxInstance) get(name *Token) (interface{}, error) {
 	return nil, NewRuntimeError(name, "Undefined property '"+name.lexeme+"'.")
 }
 
-func (s *LoxInstance) set(name *Token, value interface{}) {
+func (s *LoxInstance) set(name *Token, value interface{}) error {
+	if s.frozen {
+		return NewRuntimeError(name, "Can't modify a frozen instance.")
+	}
 	(*s.fields)[name.lexeme] = value
+	return nil
+}
+
+func (s *LoxInstance) getPrivate(owner *LoxClass, name *Token) (interface{}, error) {
+	if value, ok := s.privates[privateKey{owner, name.lexeme}]; ok {
+		return value, nil
+	}
+	if method, ok := (*owner.privateMethods)[name.lexeme]; ok {
+		return method.bind(s)
+	}
+	return nil, NewRuntimeError(name, "Undefined private member '"+name.lexeme+"'.")
+}
+
+func (s *LoxInstance) setPrivate(owner *LoxClass, name *Token, value interface{}) error {
+	if s.frozen {
+		return NewRuntimeError(name, "Can't modify a frozen instance.")
+	}
+	s.privates[privateKey{owner, name.lexeme}] = value
+	return nil
+}
+
+func (s *LoxInstance) freeze() {
+	s.frozen = true
 }
 
 func (s *LoxInstance) String() string {
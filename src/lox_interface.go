@@ -0,0 +1,17 @@
+package glox
+
+type LoxInterface struct {
+	name    string
+	methods *[]*Function
+}
+
+func NewLoxInterface(name string, methods *[]*Function) *LoxInterface {
+	return &LoxInterface{
+		name:    name,
+		methods: methods,
+	}
+}
+
+func (s *LoxInterface) String() string {
+	return s.name
+}
@@ -0,0 +1,11 @@
+package glox
+
+type LoxList struct {
+	elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{
+		elements: elements,
+	}
+}
@@ -0,0 +1,96 @@
+package glox
+
+import (
+	"math"
+	"math/big"
+)
+
+// LoxMap keeps its entries in insertion order.
+// Numbers, strings, booleans and nil are compared by value. Instances whose
+// class defines hash() are grouped by that hash and compared with "==", so
+// their equals() method is honored. Everything else is compared by identity.
+type LoxMap struct {
+	keys   []interface{}
+	slots  []interface{}
+	values map[interface{}]interface{}
+	hashed map[interface{}][]interface{}
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{
+		keys:   []interface{}{},
+		slots:  []interface{}{},
+		values: map[interface{}]interface{}{},
+		hashed: map[interface{}][]interface{}{},
+	}
+}
+
+type bigIntKey string
+type decimalKey string
+
+// hashKey gives equal numbers the same key, as they are equal with "==".
+// Decimals have keys of their own: as a decimal never equals a float, sharing
+// keys with integers would make 2d and 2.0 the same key through 2.
+func hashKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *big.Int:
+		return bigIntKey(k.String())
+	case float64:
+		if k == math.Trunc(k) && !math.IsInf(k, 0) {
+			value, _ := big.NewFloat(k).Int(nil)
+			return hashKey(normalizeInteger(value))
+		}
+	case *Decimal:
+		return decimalKey(k.stripZeros(0).String())
+	}
+	return key
+}
+
+// slot finds the entry a key is stored under. For an instance with a hash()
+// method, that is the first key with the same hash that equals it.
+func (s *LoxMap) slot(interpreter *Interpreter, key interface{}) (interface{}, interface{}, error) {
+	hash, ok, err := interpreter.callHook(key, "hash")
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return hashKey(key), nil, nil
+	}
+	if !isNumber(hash) && !isString(hash) && !isBool(hash) {
+		method := key.(*LoxInstance).class.findMethod("hash")
+		return nil, nil, NewRuntimeError(method.declaration.name, "'hash' must return a number, a string or a boolean.")
+	}
+	hash = hashKey(hash)
+	for _, existing := range s.hashed[hash] {
+		equal, err := interpreter.isEqual(key, existing)
+		if err != nil {
+			return nil, nil, err
+		}
+		if equal {
+			return existing, hash, nil
+		}
+	}
+	return key, hash, nil
+}
+
+func (s *LoxMap) set(interpreter *Interpreter, key interface{}, value interface{}) error {
+	slot, hash, err := s.slot(interpreter, key)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.values[slot]; !ok {
+		s.keys = append(s.keys, key)
+		s.slots = append(s.slots, slot)
+		if hash != nil {
+			s.hashed[hash] = append(s.hashed[hash], slot)
+		}
+	}
+	s.values[slot] = value
+	return nil
+}
+
+// lookUp finds the value of a string, number, boolean or nil key.
+func (s *LoxMap) lookUp(key interface{}) (interface{}, bool) {
+	value, ok := s.values[hashKey(key)]
+	return value, ok
+}
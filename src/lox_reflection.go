@@ -0,0 +1,382 @@
+package glox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type typeOfLoxFunction struct{}
+
+func NewTypeOfLoxFunction() *typeOfLoxFunction {
+	return &typeOfLoxFunction{}
+}
+
+func (s *typeOfLoxFunction) arity() int {
+	return 1
+}
+
+func (s *typeOfLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	return typeOf((*arguments)[0]), nil
+}
+
+func (s *typeOfLoxFunction) String() string {
+	return "<Function typeOf>"
+}
+
+// =====
+
+type classOfLoxFunction struct{}
+
+func NewClassOfLoxFunction() *classOfLoxFunction {
+	return &classOfLoxFunction{}
+}
+
+func (s *classOfLoxFunction) arity() int {
+	return 1
+}
+
+func (s *classOfLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, err := instanceArgument((*arguments)[0], "classOf")
+	if err != nil {
+		return nil, err
+	}
+	return instance.class, nil
+}
+
+func (s *classOfLoxFunction) String() string {
+	return "<Function classOf>"
+}
+
+// =====
+
+type instanceOfLoxFunction struct{}
+
+func NewInstanceOfLoxFunction() *instanceOfLoxFunction {
+	return &instanceOfLoxFunction{}
+}
+
+func (s *instanceOfLoxFunction) arity() int {
+	return 2
+}
+
+func (s *instanceOfLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, ok := (*arguments)[0].(*LoxInstance)
+	switch t := (*arguments)[1].(type) {
+	case *LoxClass:
+		return ok && instance.class.isSubclassOf(t), nil
+	case *LoxInterface:
+		return ok && instance.class.implements(t), nil
+	}
+	return nil, NewNativeError("Second argument to 'instanceOf' must be a class or an interface.")
+}
+
+func (s *instanceOfLoxFunction) String() string {
+	return "<Function instanceOf>"
+}
+
+// =====
+
+type fieldsLoxFunction struct{}
+
+func NewFieldsLoxFunction() *fieldsLoxFunction {
+	return &fieldsLoxFunction{}
+}
+
+func (s *fieldsLoxFunction) arity() int {
+	return 1
+}
+
+func (s *fieldsLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, err := instanceArgument((*arguments)[0], "fields")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range *instance.fields {
+		names = append(names, name)
+	}
+	return sortedNames(names), nil
+}
+
+func (s *fieldsLoxFunction) String() string {
+	return "<Function fields>"
+}
+
+// =====
+
+type methodsLoxFunction struct{}
+
+func NewMethodsLoxFunction() *methodsLoxFunction {
+	return &methodsLoxFunction{}
+}
+
+func (s *methodsLoxFunction) arity() int {
+	return 1
+}
+
+func (s *methodsLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	class, ok := (*arguments)[0].(*LoxClass)
+	if !ok {
+		return nil, NewNativeError("Argument to 'methods' must be a class.")
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for c := class; c != nil; c = c.superclass {
+		for name := range *c.methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return sortedNames(names), nil
+}
+
+func (s *methodsLoxFunction) String() string {
+	return "<Function methods>"
+}
+
+// =====
+
+type hasFieldLoxFunction struct{}
+
+func NewHasFieldLoxFunction() *hasFieldLoxFunction {
+	return &hasFieldLoxFunction{}
+}
+
+func (s *hasFieldLoxFunction) arity() int {
+	return 2
+}
+
+func (s *hasFieldLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, name, err := fieldArguments(*arguments, "hasField")
+	if err != nil {
+		return nil, err
+	}
+	_, ok := (*instance.fields)[name]
+	return ok, nil
+}
+
+func (s *hasFieldLoxFunction) String() string {
+	return "<Function hasField>"
+}
+
+// =====
+
+type getFieldLoxFunction struct{}
+
+func NewGetFieldLoxFunction() *getFieldLoxFunction {
+	return &getFieldLoxFunction{}
+}
+
+func (s *getFieldLoxFunction) arity() int {
+	return 2
+}
+
+func (s *getFieldLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, name, err := fieldArguments(*arguments, "getField")
+	if err != nil {
+		return nil, err
+	}
+	value, ok := (*instance.fields)[name]
+	if !ok {
+		return nil, NewNativeError("Undefined field '" + name + "'.")
+	}
+	return value, nil
+}
+
+func (s *getFieldLoxFunction) String() string {
+	return "<Function getField>"
+}
+
+// =====
+
+type setFieldLoxFunction struct{}
+
+func NewSetFieldLoxFunction() *setFieldLoxFunction {
+	return &setFieldLoxFunction{}
+}
+
+func (s *setFieldLoxFunction) arity() int {
+	return 3
+}
+
+func (s *setFieldLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	instance, name, err := fieldArguments(*arguments, "setField")
+	if err != nil {
+		return nil, err
+	}
+	if instance.frozen {
+		return nil, NewNativeError("Can't modify a frozen instance.")
+	}
+	(*instance.fields)[name] = (*arguments)[2]
+	return (*arguments)[2], nil
+}
+
+func (s *setFieldLoxFunction) String() string {
+	return "<Function setField>"
+}
+
+// =====
+
+type arityLoxFunction struct{}
+
+func NewArityLoxFunction() *arityLoxFunction {
+	return &arityLoxFunction{}
+}
+
+func (s *arityLoxFunction) arity() int {
+	return 1
+}
+
+func (s *arityLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	callable, ok := (*arguments)[0].(LoxCallable)
+	if !ok {
+		return nil, NewNativeError("Argument to 'arity' must be callable.")
+	}
+	return int64(callable.arity()), nil
+}
+
+func (s *arityLoxFunction) String() string {
+	return "<Function arity>"
+}
+
+// =====
+
+type nameOfLoxFunction struct{}
+
+func NewNameOfLoxFunction() *nameOfLoxFunction {
+	return &nameOfLoxFunction{}
+}
+
+func (s *nameOfLoxFunction) arity() int {
+	return 1
+}
+
+func (s *nameOfLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	switch t := (*arguments)[0].(type) {
+	case *LoxFunction:
+		return t.declaration.name.lexeme, nil
+	case *LoxClass:
+		return t.name, nil
+	case *LoxTrait:
+		return t.name, nil
+	case *LoxInterface:
+		return t.name, nil
+	case *LoxEnum:
+		return t.name, nil
+	case LoxCallable:
+		// Native functions print as "<Function name>".
+		return strings.TrimSuffix(strings.TrimPrefix(fmt.Sprint(t), "<Function "), ">"), nil
+	}
+	return nil, NewNativeError("Argument to 'nameOf' must be a function, class, trait, interface or enum.")
+}
+
+func (s *nameOfLoxFunction) String() string {
+	return "<Function nameOf>"
+}
+
+// =====
+
+type docOfLoxFunction struct{}
+
+func NewDocOfLoxFunction() *docOfLoxFunction {
+	return &docOfLoxFunction{}
+}
+
+func (s *docOfLoxFunction) arity() int {
+	return 1
+}
+
+func (s *docOfLoxFunction) call(_ *Interpreter, arguments *[]interface{}) (interface{}, error) {
+	var doc string
+	switch t := (*arguments)[0].(type) {
+	case *LoxFunction:
+		doc = t.declaration.doc
+	case *LoxClass:
+		doc = t.declaration.doc
+	default:
+		return nil, NewNativeError("Argument to 'docOf' must be a function or a class.")
+	}
+	if doc == "" {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+func (s *docOfLoxFunction) String() string {
+	return "<Function docOf>"
+}
+
+// =====
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	case *LoxClass:
+		return "class"
+	case *LoxInstance:
+		return "instance"
+	case *LoxTrait:
+		return "trait"
+	case *LoxInterface:
+		return "interface"
+	case *LoxEnum:
+		return "enum"
+	case *LoxEnumMember:
+		return "enum member"
+	case LoxCallable:
+		return "function"
+	}
+	switch {
+	case isInteger(value):
+		return "integer"
+	case isDecimal(value):
+		return "decimal"
+	case isFloat(value):
+		return "float"
+	}
+	return "unknown"
+}
+
+func instanceArgument(value interface{}, function string) (*LoxInstance, error) {
+	instance, ok := value.(*LoxInstance)
+	if !ok {
+		return nil, NewNativeError("Argument to '" + function + "' must be an instance.")
+	}
+	return instance, nil
+}
+
+func fieldArguments(arguments []interface{}, function string) (*LoxInstance, string, error) {
+	instance, err := instanceArgument(arguments[0], function)
+	if err != nil {
+		return nil, "", err
+	}
+	name, ok := arguments[1].(string)
+	if !ok {
+		return nil, "", NewNativeError("Field name passed to '" + function + "' must be a string.")
+	}
+	if strings.HasPrefix(name, "#") {
+		return nil, "", NewNativeError("Can't access private member '" + name + "' through '" + function + "'.")
+	}
+	return instance, name, nil
+}
+
+func sortedNames(names []string) *LoxList {
+	sort.Strings(names)
+	elements := make([]interface{}, len(names))
+	for i, name := range names {
+		elements[i] = name
+	}
+	return NewLoxList(elements)
+}
@@ -0,0 +1,17 @@
+package glox
+
+type LoxTrait struct {
+	name    string
+	methods *map[string]*LoxFunction
+}
+
+func NewLoxTrait(name string, methods *map[string]*LoxFunction) *LoxTrait {
+	return &LoxTrait{
+		name:    name,
+		methods: methods,
+	}
+}
+
+func (s *LoxTrait) String() string {
+	return s.name
+}
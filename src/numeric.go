@@ -0,0 +1,266 @@
+package glox
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Lox numbers are either integers, floats or decimals.
+// Integers are int64 and are promoted to *big.Int when an operation overflows;
+// a *big.Int that fits in an int64 is always demoted back, so every integer has one representation.
+// Mixing an integer with a float gives a float, and mixing an integer with a decimal gives a decimal.
+// Dividing integers with "/" gives a float too; div() divides them exactly.
+// Integers and floats are compared exactly, not through float64.
+// Decimals and floats can't be mixed in arithmetic or comparisons, as that would lose the exactness of decimals,
+// and a decimal is never equal to a float.
+
+func isNumber(obj interface{}) bool {
+	switch obj.(type) {
+	case int64, *big.Int, float64, *Decimal:
+		return true
+	}
+	return false
+}
+
+func isDecimal(obj interface{}) bool {
+	_, ok := obj.(*Decimal)
+	return ok
+}
+
+func isInteger(obj interface{}) bool {
+	switch obj.(type) {
+	case int64, *big.Int:
+		return true
+	}
+	return false
+}
+
+func toFloat64(obj interface{}) float64 {
+	switch v := obj.(type) {
+	case int64:
+		return float64(v)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f
+	case float64:
+		return v
+	case *Decimal:
+		f, _ := new(big.Rat).SetFrac(v.unscaled, pow10(v.scale)).Float64()
+		return f
+	}
+	return math.NaN()
+}
+
+func toBigInt(obj interface{}) *big.Int {
+	switch v := obj.(type) {
+	case int64:
+		return big.NewInt(v)
+	case *big.Int:
+		return v
+	}
+	return nil
+}
+
+func normalizeInteger(value *big.Int) interface{} {
+	if value.IsInt64() {
+		return value.Int64()
+	}
+	return value
+}
+
+// arithmetic applies "+", "-", "*" or "/" to two numbers.
+func arithmetic(operator *Token, left interface{}, right interface{}, context *decimalContext) (interface{}, error) {
+	if isDecimal(left) || isDecimal(right) {
+		return decimalArithmetic(operator, toDecimal(left), toDecimal(right), context)
+	}
+	if !isInteger(left) || !isInteger(right) {
+		l, r := toFloat64(left), toFloat64(right)
+		switch operator.tokenType {
+		case TokenPlus:
+			return l + r, nil
+		case TokenMinus:
+			return l - r, nil
+		case TokenStar:
+			return l * r, nil
+		case TokenSlash:
+			return l / r, nil
+		}
+		return nil, nil
+	}
+	if operator.tokenType == TokenSlash {
+		return integerDivision(left, right), nil
+	}
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			if value, ok := int64Arithmetic(operator.tokenType, l, r); ok {
+				return value, nil
+			}
+		}
+	}
+	l, r := toBigInt(left), toBigInt(right)
+	value := new(big.Int)
+	switch operator.tokenType {
+	case TokenPlus:
+		value.Add(l, r)
+	case TokenMinus:
+		value.Sub(l, r)
+	case TokenStar:
+		value.Mul(l, r)
+	}
+	return normalizeInteger(value), nil
+}
+
+// maxExactFloat is the largest integer below which every integer is a float64.
+const maxExactFloat = 1 << 53
+
+// integerDivision is the float nearest to the quotient of two integers, so
+// "/" means the same for integers as for floats.
+func integerDivision(left interface{}, right interface{}) float64 {
+	l, lok := left.(int64)
+	r, rok := right.(int64)
+	if lok && rok && -maxExactFloat <= l && l <= maxExactFloat && -maxExactFloat <= r && r <= maxExactFloat {
+		return float64(l) / float64(r)
+	}
+	if toBigInt(right).Sign() == 0 {
+		return toFloat64(left) / 0
+	}
+	value, _ := new(big.Rat).SetFrac(toBigInt(left), toBigInt(right)).Float64()
+	return value
+}
+
+// integerQuotient divides integers exactly, truncating toward zero.
+func integerQuotient(left interface{}, right interface{}) interface{} {
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok && !(l == math.MinInt64 && r == -1) {
+			return l / r
+		}
+	}
+	return normalizeInteger(new(big.Int).Quo(toBigInt(left), toBigInt(right)))
+}
+
+// int64Arithmetic reports false when the result doesn't fit in an int64.
+func int64Arithmetic(tokenType TokenType, l int64, r int64) (int64, bool) {
+	switch tokenType {
+	case TokenPlus:
+		value := l + r
+		return value, (value > l) == (r > 0)
+	case TokenMinus:
+		value := l - r
+		return value, (value < l) == (r > 0)
+	case TokenStar:
+		if l == 0 || r == 0 {
+			return 0, true
+		}
+		value := l * r
+		return value, value/r == l && !(l == -1 && r == math.MinInt64) && !(r == -1 && l == math.MinInt64)
+	}
+	return 0, false
+}
+
+func negate(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return new(big.Int).Neg(big.NewInt(v))
+		}
+		return -v
+	case *big.Int:
+		return normalizeInteger(new(big.Int).Neg(v))
+	case float64:
+		return -v
+	case *Decimal:
+		return v.neg()
+	}
+	return nil
+}
+
+// compareNumbers applies "<", "<=", ">" or ">=" to two numbers.
+func compareNumbers(tokenType TokenType, left interface{}, right interface{}) bool {
+	if isDecimal(left) || isDecimal(right) {
+		return compareSign(tokenType, toDecimal(left).cmp(toDecimal(right)))
+	}
+	if c, ok := compareIntegerFloat(left, right); ok {
+		return compareSign(tokenType, c)
+	}
+	if !isInteger(left) || !isInteger(right) {
+		l, r := toFloat64(left), toFloat64(right)
+		switch tokenType {
+		case TokenGreater:
+			return l > r
+		case TokenGreaterEqual:
+			return l >= r
+		case TokenLess:
+			return l < r
+		case TokenLessEqual:
+			return l <= r
+		}
+		return false
+	}
+	return compareSign(tokenType, toBigInt(left).Cmp(toBigInt(right)))
+}
+
+func compareSign(tokenType TokenType, c int) bool {
+	switch tokenType {
+	case TokenGreater:
+		return c > 0
+	case TokenGreaterEqual:
+		return c >= 0
+	case TokenLess:
+		return c < 0
+	case TokenLessEqual:
+		return c <= 0
+	}
+	return false
+}
+
+// numbersEqual never finds a decimal equal to a float, as they can't be compared exactly.
+func numbersEqual(left interface{}, right interface{}) bool {
+	if isDecimal(left) || isDecimal(right) {
+		if isFloat(left) || isFloat(right) {
+			return false
+		}
+		return toDecimal(left).cmp(toDecimal(right)) == 0
+	}
+	if c, ok := compareIntegerFloat(left, right); ok {
+		return c == 0
+	}
+	if isFloat(left) || isFloat(right) {
+		return toFloat64(left) == toFloat64(right)
+	}
+	return toBigInt(left).Cmp(toBigInt(right)) == 0
+}
+
+// compareIntegerFloat compares an integer with a finite float exactly, as
+// float64 can't hold every integer. It reports false for other operands.
+func compareIntegerFloat(left interface{}, right interface{}) (int, bool) {
+	if isInteger(left) && isFloat(right) {
+		c, ok := compareIntegerFloat(right, left)
+		return -c, ok
+	}
+	f, ok := left.(float64)
+	if !ok || !isInteger(right) || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+	return new(big.Rat).SetFloat64(f).Cmp(new(big.Rat).SetInt(toBigInt(right))), true
+}
+
+func formatNumber(obj interface{}) string {
+	switch v := obj.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *big.Int:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%v", v)
+	case *Decimal:
+		return v.String()
+	}
+	return ""
+}
+
+func isFloat(obj interface{}) bool {
+	_, ok := obj.(float64)
+	return ok
+}
@@ -3,12 +3,14 @@ package glox
 type Parser struct {
 	tokens  *[]*Token
 	current int
+	info    *sourceInfo
 }
 
 func NewParser(tokens *[]*Token) *Parser {
 	return &Parser{
 		tokens:  tokens,
 		current: 0,
+		info:    newSourceInfo(),
 	}
 }
 
@@ -16,6 +18,18 @@ func (s *Parser) ParseExpressionForTest() (Expr, error) {
 	return s.expression()
 }
 
+// ParseExpression parses tokens holding a single expression.
+func (s *Parser) ParseExpression() (Expr, error) {
+	expr, err := s.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !s.isAtEnd() {
+		return nil, NewParserError(s.peek(), "Expect end of expression.")
+	}
+	return expr, nil
+}
+
 func (s *Parser) Parse() ([]Stmt, error) {
 	// program        → declaration* EOF ;
 	var statements []Stmt
@@ -49,15 +63,46 @@ func (s *Parser) block() ([]Stmt, error) {
 }
 
 // declaration    → classDecl
+//                | constDecl
+//                | enumDecl
 //                | funDecl
+//                | traitDecl
 //                | varDecl
 //                | statement ;
-func (s *Parser) declaration() (Stmt, error) {
+func (s *Parser) declaration() (stmt Stmt, err error) {
+	defer s.recordSpan(s.peek(), &stmt, &err)
 	if s.match(TokenClass) {
-		return s.classDeclaration()
+		return s.classDeclaration(nil)
+	}
+	if s.match(TokenAbstract) {
+		abstract := s.previous()
+		_, err := s.consume(TokenClass, "Expect 'class' after 'abstract'.")
+		if err != nil {
+			return nil, err
+		}
+		return s.classDeclaration(abstract)
+	}
+	if s.match(TokenConst) {
+		stmt, err := s.constDeclaration()
+		if _, ok := err.(*ParserError); ok {
+			s.synchronize()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	}
+	if s.match(TokenEnum) {
+		return s.enumDeclaration()
 	}
 	if s.match(TokenFun) {
-		return s.function("function")
+		return s.function("function", s.previous().doc)
+	}
+	if s.match(TokenInterface) {
+		return s.interfaceDeclaration()
+	}
+	if s.match(TokenTrait) {
+		return s.traitDeclaration()
 	}
 	if s.match(TokenVar) {
 		stmt, err := s.varDeclaration()
@@ -70,7 +115,7 @@ func (s *Parser) declaration() (Stmt, error) {
 		}
 		return stmt, nil
 	}
-	stmt, err := s.statement()
+	stmt, err = s.statement()
 	if _, ok := err.(*ParserError); ok {
 		s.synchronize()
 	}
@@ -80,9 +125,15 @@ func (s *Parser) declaration() (Stmt, error) {
 	return stmt, nil
 }
 
-// classDecl      → "class" IDENTIFIER ( "<" IDENTIFIER )?
-//                  "{" function* "}" ;
-func (s *Parser) classDeclaration() (Stmt, error) {
+// classDecl      → "abstract"? "class" IDENTIFIER ( "<" IDENTIFIER )?
+//                  ( "implements" IDENTIFIER ( "," IDENTIFIER )* )?
+//                  ( "with" IDENTIFIER ( "," IDENTIFIER )* )?
+//                  "{" ( function | "abstract" signature )* "}" ;
+func (s *Parser) classDeclaration(abstract *Token) (Stmt, error) {
+	doc := s.previous().doc
+	if abstract != nil {
+		doc = abstract.doc
+	}
 	className, err := s.consume(TokenIdentifier, "Expect class name.")
 	if err != nil {
 		return nil, err
@@ -95,48 +146,243 @@ func (s *Parser) classDeclaration() (Stmt, error) {
 		}
 		superclass = NewVariable(s.previous())
 	}
+	var interfaces []*Variable
+	if s.match(TokenImplements) {
+		interfaces, err = s.variableList("interface")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var traits []*Variable
+	if s.match(TokenWith) {
+		traits, err = s.variableList("trait")
+		if err != nil {
+			return nil, err
+		}
+	}
 	_, err = s.consume(TokenLeftBrace, "Expect '{' before class body.")
 	if err != nil {
 		return nil, err
 	}
 	var methods []*Function
 	for !s.check(TokenRightBrace) && !s.isAtEnd() {
-		f, err := s.function("method")
+		var method *Function
+		doc := s.peek().doc
+		if s.match(TokenAbstract) {
+			if abstract == nil {
+				return nil, NewParserError(s.previous(), "Only abstract classes can declare abstract methods.")
+			}
+			method, err = s.signature("method", doc)
+		} else {
+			method, err = s.function("method", doc)
+		}
 		if err != nil {
 			return nil, err
 		}
-		methods = append(methods, f)
+		methods = append(methods, method)
 	}
 	_, err = s.consume(TokenRightBrace, "Expect '}' after class body.")
 	if err != nil {
 		return nil, err
 	}
-	return NewClass(className, superclass, &methods), nil
+	return NewClass(className, superclass, &interfaces, &traits, &methods, abstract, doc), nil
+}
+
+func (s *Parser) variableList(kind string) ([]*Variable, error) {
+	var variables []*Variable
+	for {
+		_, err := s.consume(TokenIdentifier, "Expect "+kind+" name.")
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, NewVariable(s.previous()))
+		if !s.match(TokenComma) {
+			return variables, nil
+		}
+	}
+}
+
+// interfaceDecl  → "interface" IDENTIFIER "{" signature* "}" ;
+func (s *Parser) interfaceDeclaration() (Stmt, error) {
+	interfaceName, err := s.consume(TokenIdentifier, "Expect interface name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenLeftBrace, "Expect '{' before interface body.")
+	if err != nil {
+		return nil, err
+	}
+	var methods []*Function
+	for !s.check(TokenRightBrace) && !s.isAtEnd() {
+		method, err := s.signature("method", s.peek().doc)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	_, err = s.consume(TokenRightBrace, "Expect '}' after interface body.")
+	if err != nil {
+		return nil, err
+	}
+	return NewInterface(interfaceName, &methods), nil
+}
+
+// traitDecl      → "trait" IDENTIFIER "{" function* "}" ;
+func (s *Parser) traitDeclaration() (Stmt, error) {
+	traitName, err := s.consume(TokenIdentifier, "Expect trait name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenLeftBrace, "Expect '{' before trait body.")
+	if err != nil {
+		return nil, err
+	}
+	methods, err := s.methods("trait")
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range methods {
+		if method.name.tokenType == TokenPrivateIdentifier {
+			return nil, NewParserError(method.name, "A trait can't declare private methods.")
+		}
+	}
+	return NewTrait(traitName, &methods), nil
+}
+
+func (s *Parser) methods(kind string) ([]*Function, error) {
+	var methods []*Function
+	for !s.check(TokenRightBrace) && !s.isAtEnd() {
+		f, err := s.function("method", s.peek().doc)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, f)
+	}
+	_, err := s.consume(TokenRightBrace, "Expect '}' after "+kind+" body.")
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// enumDecl       → "enum" IDENTIFIER "{" ( IDENTIFIER ( "," IDENTIFIER )* ","? )? "}" ;
+func (s *Parser) enumDeclaration() (Stmt, error) {
+	enumName, err := s.consume(TokenIdentifier, "Expect enum name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenLeftBrace, "Expect '{' before enum body.")
+	if err != nil {
+		return nil, err
+	}
+	var members []*Token
+	for !s.check(TokenRightBrace) && !s.isAtEnd() {
+		member, err := s.consume(TokenIdentifier, "Expect enum member name.")
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+		if !s.match(TokenComma) {
+			break
+		}
+	}
+	_, err = s.consume(TokenRightBrace, "Expect '}' after enum body.")
+	if err != nil {
+		return nil, err
+	}
+	return NewEnum(enumName, &members), nil
 }
 
 // funDecl        → "fun" function ;
-// function       → IDENTIFIER "(" parameters? ")" block ;
-func (s *Parser) function(kind string) (*Function, error) {
-	funcName, err := s.consume(TokenIdentifier, "Expect "+kind+" name.")
+// function       → IDENTIFIER "(" parameters? ")" annotation? block ;
+// method         → ( IDENTIFIER | PRIVATE_IDENTIFIER ) "(" parameters? ")" annotation? block ;
+func (s *Parser) function(kind string, doc string) (*Function, error) {
+	start := s.peek()
+	function, err := s.header(kind, doc)
 	if err != nil {
 		return nil, err
 	}
+
+	_, err = s.consume(TokenLeftBrace, "Expect '{' before "+kind+" body.")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.block()
+	if err != nil {
+		return nil, err
+	}
+	function.body = &body
+	s.info.spans[function] = &span{start: start, end: s.previous()}
+	return function, nil
+}
+
+// signature      → IDENTIFIER "(" parameters? ")" annotation? ";" ;
+//
+// A signature declares a method without a body; its Function has a nil body.
+func (s *Parser) signature(kind string, doc string) (*Function, error) {
+	start := s.peek()
+	function, err := s.header(kind, doc)
+	if err != nil {
+		return nil, err
+	}
+	if function.name.tokenType == TokenPrivateIdentifier {
+		return nil, NewParserError(function.name, "A method without a body can't be private.")
+	}
+	_, err = s.consume(TokenSemicolon, "Expect ';' after "+kind+" signature.")
+	if err != nil {
+		return nil, err
+	}
+	s.info.spans[function] = &span{start: start, end: s.previous()}
+	return function, nil
+}
+
+// header parses a function up to its body, which is left nil.
+func (s *Parser) header(kind string, doc string) (*Function, error) {
+	var funcName *Token
+	var err error
+	if kind == "method" && s.match(TokenPrivateIdentifier) {
+		funcName = s.previous()
+	} else {
+		funcName, err = s.consume(TokenIdentifier, "Expect "+kind+" name.")
+		if err != nil {
+			return nil, err
+		}
+	}
 	_, err = s.consume(TokenLeftParen, "Expect '(' after "+kind+" name.")
 	if err != nil {
 		return nil, err
 	}
 	var parameters []*Token
+	var parameterTypes []*Token
+	var rest *Token
 	if !s.check(TokenRightParen) {
 		for {
 			// if len(parameters) >= 255 {
 			// 	return nil, NewParserError(s.peek(), "Can't have more than 255 arguments.")
 			// }
 
+			if s.match(TokenEllipsis) {
+				rest, err = s.consume(TokenIdentifier, "Expect rest parameter name.")
+				if err != nil {
+					return nil, err
+				}
+				if s.check(TokenComma) {
+					return nil, NewParserError(s.peek(), "Rest parameter must be last.")
+				}
+				break
+			}
+
 			parameterName, err := s.consume(TokenIdentifier, "Expect parameter name.")
 			if err != nil {
 				return nil, err
 			}
+			parameterType, err := s.annotation()
+			if err != nil {
+				return nil, err
+			}
 			parameters = append(parameters, parameterName)
+			parameterTypes = append(parameterTypes, parameterType)
 
 			if !s.match(TokenComma) {
 				break
@@ -147,27 +393,41 @@ func (s *Parser) function(kind string) (*Function, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	_, err = s.consume(TokenLeftBrace, "Expect '{' before "+kind+" body.")
+	returnType, err := s.annotation()
 	if err != nil {
 		return nil, err
 	}
-
-	body, err := s.block()
-	if err != nil {
-		return nil, err
-	}
-	return NewFunction(funcName, &parameters, &body), nil
+	return NewFunction(funcName, &parameters, &parameterTypes, rest, returnType, nil, doc), nil
 }
 
-// parameters     → IDENTIFIER ( "," IDENTIFIER )* ;
+// parameters     → ( IDENTIFIER annotation? ( "," IDENTIFIER annotation? )* ( "," "..." IDENTIFIER )? )
+//                | "..." IDENTIFIER ;
+
+// annotation     → ":" IDENTIFIER ;
+//
+// annotation returns the type name, or nil when there is no annotation.
+func (s *Parser) annotation() (*Token, error) {
+	if !s.match(TokenColon) {
+		return nil, nil
+	}
+	return s.consume(TokenIdentifier, "Expect type name after ':'.")
+}
 
-// varDecl        → "var" IDENTIFIER ( "=" expression )? ";" ;
+// varDecl        → "var" IDENTIFIER annotation? ( "=" expression )? ";"
+//                | "var" binding "=" expression ";" ;
 func (s *Parser) varDeclaration() (Stmt, error) {
+	doc := s.previous().doc
+	if s.check(TokenLeftBracket) || s.check(TokenLeftBrace) {
+		return s.varPatternDeclaration()
+	}
 	name, err := s.consume(TokenIdentifier, "Expect variable name.")
 	if err != nil {
 		return nil, err
 	}
+	annotation, err := s.annotation()
+	if err != nil {
+		return nil, err
+	}
 	var initializer Expr = nil
 	if s.match(TokenEqual) {
 		initializer, err = s.expression()
@@ -179,23 +439,92 @@ func (s *Parser) varDeclaration() (Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewVar(name, initializer), nil
+	return NewVar(name, annotation, initializer, doc), nil
+}
+
+func (s *Parser) varPatternDeclaration() (Stmt, error) {
+	keyword := s.previous()
+	pattern, err := s.binding()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenEqual, "Expect '=' after destructuring pattern.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := s.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenSemicolon, "Expect ';' after variable declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return NewVarPattern(keyword, pattern, initializer), nil
+}
+
+// binding        → IDENTIFIER
+//                | "[" ( binding ( "," binding )* ( "," "..." IDENTIFIER )? )? "]"
+//                | "{" ( IDENTIFIER ( ":" binding )? ( "," IDENTIFIER ( ":" binding )? )* )? "}" ;
+func (s *Parser) binding() (Expr, error) {
+	if s.match(TokenLeftBracket) {
+		bracket := s.previous()
+		elements, err := s.patternList(s.binding, TokenRightBracket, "Expect ']' after list pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return NewListLiteral(bracket, &elements), nil
+	}
+	if s.match(TokenLeftBrace) {
+		return s.objectPattern(s.binding)
+	}
+	name, err := s.consume(TokenIdentifier, "Expect variable name in destructuring pattern.")
+	if err != nil {
+		return nil, err
+	}
+	return NewVariable(name), nil
+}
+
+// constDecl      → "const" IDENTIFIER "=" expression ";" ;
+func (s *Parser) constDeclaration() (Stmt, error) {
+	name, err := s.consume(TokenIdentifier, "Expect constant name.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenEqual, "Expect '=' after constant name.")
+	if err != nil {
+		return nil, err
+	}
+	initializer, err := s.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenSemicolon, "Expect ';' after constant declaration.")
+	if err != nil {
+		return nil, err
+	}
+	return NewConst(name, initializer), nil
 }
 
 // statement      → exprStmt
 //                | forStmt
 //                | ifStmt
+//                | matchStmt
 //                | printStmt
 //                | returnStmt
 //                | whileStmt
 //                | block ;
-func (s *Parser) statement() (Stmt, error) {
+func (s *Parser) statement() (stmt Stmt, err error) {
+	defer s.recordSpan(s.peek(), &stmt, &err)
 	if s.match(TokenFor) {
 		return s.forStatement()
 	}
 	if s.match(TokenIf) {
 		return s.ifStatement()
 	}
+	if s.match(TokenMatch) {
+		return s.matchStatement()
+	}
 	if s.match(TokenPrint) {
 		return s.printStatement()
 	}
@@ -287,6 +616,7 @@ func (s *Parser) forStatement() (Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	loop := &forLoop{initializer: initializer, condition: condition, increment: increment, body: body}
 
 	if increment != nil {
 		var blockList []Stmt
@@ -307,6 +637,7 @@ func (s *Parser) forStatement() (Stmt, error) {
 		body = NewBlock(&blockList)
 	}
 
+	s.info.loops[body] = loop
 	return body, nil
 }
 
@@ -339,6 +670,183 @@ func (s *Parser) ifStatement() (Stmt, error) {
 	return NewIf(condition, thenBranch, elseBranch), nil
 }
 
+// matchStmt      → "match" "(" expression ")" "{" matchCase* "}" ;
+// matchCase      → "case" pattern ( "," pattern )* "=>" statement ;
+func (s *Parser) matchStatement() (Stmt, error) {
+	keyword := s.previous()
+	_, err := s.consume(TokenLeftParen, "Expect '(' after 'match'.")
+	if err != nil {
+		return nil, err
+	}
+	subject, err := s.expression()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenRightParen, "Expect ')' after match subject.")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.consume(TokenLeftBrace, "Expect '{' before match cases.")
+	if err != nil {
+		return nil, err
+	}
+	var cases []*MatchCase
+	for !s.check(TokenRightBrace) && !s.isAtEnd() {
+		caseKeyword, err := s.consume(TokenCase, "Expect 'case'.")
+		if err != nil {
+			return nil, err
+		}
+		var patterns []Expr
+		for {
+			p, err := s.pattern()
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p)
+			if !s.match(TokenComma) {
+				break
+			}
+		}
+		_, err = s.consume(TokenArrow, "Expect '=>' after case patterns.")
+		if err != nil {
+			return nil, err
+		}
+		body, err := s.statement()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, NewMatchCase(caseKeyword, &patterns, body))
+	}
+	_, err = s.consume(TokenRightBrace, "Expect '}' after match cases.")
+	if err != nil {
+		return nil, err
+	}
+	return NewMatch(keyword, subject, &cases), nil
+}
+
+// pattern        → "_" | IDENTIFIER
+//                | "-"? NUMBER | STRING | "true" | "false" | "nil"
+//                | IDENTIFIER ( "." IDENTIFIER )+
+//                | IDENTIFIER "(" ( pattern ( "," pattern )* )? ")"
+//                | "[" ( pattern ( "," pattern )* ( "," "..." IDENTIFIER )? )? "]"
+//                | "{" ( IDENTIFIER ( ":" pattern )? ( "," IDENTIFIER ( ":" pattern )? )* )? "}" ;
+func (s *Parser) pattern() (Expr, error) {
+	if s.match(TokenNumber, TokenString) {
+		return s.literal(s.previous().literal, s.previous().lexeme), nil
+	}
+	if s.match(TokenMinus) {
+		number, err := s.consume(TokenNumber, "Expect number after '-' in pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return s.literal(negate(number.literal), "-"+number.lexeme), nil
+	}
+	if s.match(TokenTrue) {
+		return NewLiteral(true), nil
+	}
+	if s.match(TokenFalse) {
+		return NewLiteral(false), nil
+	}
+	if s.match(TokenNil) {
+		return NewLiteral(nil), nil
+	}
+	if s.match(TokenLeftBracket) {
+		bracket := s.previous()
+		elements, err := s.patternList(s.pattern, TokenRightBracket, "Expect ']' after list pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return NewListLiteral(bracket, &elements), nil
+	}
+	if s.match(TokenLeftBrace) {
+		return s.objectPattern(s.pattern)
+	}
+	name, err := s.consume(TokenIdentifier, "Expect pattern.")
+	if err != nil {
+		return nil, err
+	}
+	var expr Expr = NewVariable(name)
+	if s.match(TokenLeftParen) {
+		arguments, err := s.patternList(s.pattern, TokenRightParen, "Expect ')' after class pattern.")
+		if err != nil {
+			return nil, err
+		}
+		return NewCall(expr, s.previous(), &arguments), nil
+	}
+	for s.match(TokenDot) {
+		name, err := s.consume(TokenIdentifier, "Expect property name after '.'.")
+		if err != nil {
+			return nil, err
+		}
+		expr = NewGet(expr, name)
+	}
+	return expr, nil
+}
+
+func (s *Parser) patternList(element func() (Expr, error), closing TokenType, message string) ([]Expr, error) {
+	var patterns []Expr
+	if !s.check(closing) {
+		for {
+			if closing == TokenRightBracket && s.match(TokenEllipsis) {
+				ellipsis := s.previous()
+				name, err := s.consume(TokenIdentifier, "Expect name after '...' in list pattern.")
+				if err != nil {
+					return nil, err
+				}
+				patterns = append(patterns, NewSpread(ellipsis, NewVariable(name)))
+				if !s.check(closing) {
+					return nil, NewParserError(s.peek(), "Rest element must be last in a list pattern.")
+				}
+				break
+			}
+			p, err := element()
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p)
+			if !s.match(TokenComma) {
+				break
+			}
+		}
+	}
+	_, err := s.consume(closing, message)
+	if err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func (s *Parser) objectPattern(value func() (Expr, error)) (Expr, error) {
+	brace := s.previous()
+	var keys []*Token
+	var values []Expr
+	if !s.check(TokenRightBrace) {
+		for {
+			key, err := s.consume(TokenIdentifier, "Expect field name in object pattern.")
+			if err != nil {
+				return nil, err
+			}
+			var v Expr = NewVariable(key)
+			if s.match(TokenColon) {
+				v, err = value()
+				if err != nil {
+					return nil, err
+				}
+			}
+			keys = append(keys, key)
+			values = append(values, v)
+			if !s.match(TokenComma) {
+				break
+			}
+		}
+	}
+	_, err := s.consume(TokenRightBrace, "Expect '}' after object pattern.")
+	if err != nil {
+		return nil, err
+	}
+	return NewObjectPattern(brace, &keys, &values), nil
+}
+
 // whileStmt      → "while" "(" expression ")" statement ;
 func (s *Parser) whileStatement() (Stmt, error) {
 	_, err := s.consume(TokenLeftParen, "Expect '(' after 'while'.")
@@ -360,7 +868,7 @@ func (s *Parser) whileStatement() (Stmt, error) {
 	return NewWhile(condition, body), nil
 }
 
-// returnStmt     → "return" expression? ";" ;
+// returnStmt     → "return" ( expression ( "," expression )* )? ";" ;
 func (s *Parser) returnStatement() (Stmt, error) {
 	keyword := s.previous()
 	var value Expr
@@ -371,6 +879,18 @@ func (s *Parser) returnStatement() (Stmt, error) {
 			return nil, err
 		}
 	}
+	if value != nil && s.check(TokenComma) {
+		// Multiple return values are returned as a list.
+		values := []Expr{value}
+		for s.match(TokenComma) {
+			value, err = s.expression()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		value = NewListLiteral(keyword, &values)
+	}
 	_, err = s.consume(TokenSemicolon, "Expect ';' after return value.")
 	if err != nil {
 		return nil, err
@@ -387,7 +907,9 @@ func (s *Parser) expression() (Expr, error) {
 }
 
 // assignment     → ( call "." )? IDENTIFIER "=" assignment
+//                | "[" target ( "," target )* "]" "=" assignment
 //                | logic_or ;
+// target         → ( call "." )? IDENTIFIER | "..." IDENTIFIER | "[" target ( "," target )* "]" ;
 func (s *Parser) assignment() (Expr, error) {
 	expr, err := s.or()
 	if err != nil {
@@ -404,12 +926,33 @@ func (s *Parser) assignment() (Expr, error) {
 			return NewAssign(name, value), nil
 		} else if get, ok := expr.(*Get); ok {
 			return NewSet(get.object, get.name, value), nil
+		} else if list, ok := expr.(*ListLiteral); ok && isAssignmentPattern(list) {
+			return NewAssignPattern(list, equals, value), nil
 		}
 		return nil, NewParserError(equals, "Invalid assignment target.")
 	}
 	return expr, nil
 }
 
+func isAssignmentPattern(expr Expr) bool {
+	switch e := expr.(type) {
+	case *Variable, *Get:
+		return true
+	case *ListLiteral:
+		for i, element := range *e.elements {
+			if spread, ok := element.(*Spread); ok {
+				if _, ok := spread.expression.(*Variable); !ok || i != len(*e.elements)-1 {
+					return false
+				}
+			} else if !isAssignmentPattern(element) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 // logic_or       → logic_and ( "or" logic_and )* ;
 func (s *Parser) or() (Expr, error) {
 	expr, err := s.and()
@@ -525,7 +1068,7 @@ func (s *Parser) unary() (Expr, error) {
 	return s.call()
 }
 
-// call           → primary ( "(" arguments? ")" | "." IDENTIFIER )* ;
+// call           → primary ( "(" arguments? ")" | "." ( IDENTIFIER | PRIVATE_IDENTIFIER ) )* ;
 func (s *Parser) call() (Expr, error) {
 	expr, err := s.primary()
 	if err != nil {
@@ -538,6 +1081,10 @@ func (s *Parser) call() (Expr, error) {
 				return nil, err
 			}
 		} else if s.match(TokenDot) {
+			if s.match(TokenPrivateIdentifier) {
+				expr = NewGet(expr, s.previous())
+				continue
+			}
 			name, err := s.consume(TokenIdentifier, "Expect property name after '.'.")
 			if err != nil {
 				return nil, err
@@ -552,17 +1099,19 @@ func (s *Parser) call() (Expr, error) {
 
 func (s *Parser) finishCall(callee Expr) (Expr, error) {
 	var arguments []Expr
+	var ends []int
 	if !s.check(TokenRightParen) {
 		for {
 			// if len(arguments) >= 255 {
 			// 	return nil, NewParserError(s.peek(), "Can't have more than 255 arguments.")
 			// }
 
-			expr, err := s.expression()
+			expr, err := s.spreadable()
 			if err != nil {
 				return nil, err
 			}
 			arguments = append(arguments, expr)
+			ends = append(ends, s.previous().line)
 
 			if !s.match(TokenComma) {
 				break
@@ -573,17 +1122,36 @@ func (s *Parser) finishCall(callee Expr) (Expr, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewCall(callee, paren, &arguments), nil
+	call := NewCall(callee, paren, &arguments)
+	s.info.elements[call] = &elementLines{ends: ends, close: paren.line}
+	return call, nil
 }
 
-// arguments      → expression ( "," expression )* ;
+// arguments      → spreadable ( "," spreadable )* ;
+
+// spreadable     → "..."? expression ;
+func (s *Parser) spreadable() (Expr, error) {
+	if s.match(TokenEllipsis) {
+		ellipsis := s.previous()
+		expr, err := s.expression()
+		if err != nil {
+			return nil, err
+		}
+		return NewSpread(ellipsis, expr), nil
+	}
+	return s.expression()
+}
 
 // primary        → "true" | "false" | "nil" | "this"
 //                | NUMBER | STRING | IDENTIFIER | "(" expression ")"
+//                | "[" ( spreadable ( "," spreadable )* ","? )? "]"
+//                | "{" ( entry ( "," entry )* ","? )? "}"
 //                | "super" "." IDENTIFIER ;
+// entry          → ( IDENTIFIER | STRING | NUMBER | "[" expression "]" ) ":" expression
+//                | "..." expression ;
 func (s *Parser) primary() (Expr, error) {
 	if s.match(TokenNumber, TokenString) {
-		return NewLiteral(s.previous().literal), nil
+		return s.literal(s.previous().literal, s.previous().lexeme), nil
 	}
 	if s.match(TokenSuper) {
 		keyword := s.previous()
@@ -623,9 +1191,108 @@ func (s *Parser) primary() (Expr, error) {
 		}
 		return NewGrouping(expr), nil
 	}
+	if s.match(TokenLeftBracket) {
+		bracket := s.previous()
+		var elements []Expr
+		var ends []int
+		for !s.check(TokenRightBracket) && !s.isAtEnd() {
+			element, err := s.spreadable()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+			ends = append(ends, s.previous().line)
+			if !s.match(TokenComma) {
+				break
+			}
+		}
+		closing, err := s.consume(TokenRightBracket, "Expect ']' after list elements.")
+		if err != nil {
+			return nil, err
+		}
+		list := NewListLiteral(bracket, &elements)
+		s.info.elements[list] = &elementLines{ends: ends, close: closing.line}
+		return list, nil
+	}
+	if s.match(TokenLeftBrace) {
+		return s.mapLiteral()
+	}
 	return nil, NewParserError(s.peek(), "Expect expression.")
 }
 
+// A spread entry of a map literal is stored as a Spread key with a nil value.
+func (s *Parser) mapLiteral() (Expr, error) {
+	brace := s.previous()
+	var keys []Expr
+	var values []Expr
+	var ends []int
+	for !s.check(TokenRightBrace) && !s.isAtEnd() {
+		if s.match(TokenEllipsis) {
+			ellipsis := s.previous()
+			expr, err := s.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, NewSpread(ellipsis, expr))
+			values = append(values, nil)
+		} else {
+			var key Expr
+			if s.match(TokenIdentifier) {
+				key = s.literal(s.previous().lexeme, s.previous().lexeme)
+			} else if s.match(TokenString, TokenNumber) {
+				key = s.literal(s.previous().literal, s.previous().lexeme)
+			} else if s.match(TokenLeftBracket) {
+				var err error
+				key, err = s.expression()
+				if err != nil {
+					return nil, err
+				}
+				_, err = s.consume(TokenRightBracket, "Expect ']' after computed key.")
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				return nil, NewParserError(s.peek(), "Expect map key.")
+			}
+			_, err := s.consume(TokenColon, "Expect ':' after map key.")
+			if err != nil {
+				return nil, err
+			}
+			value, err := s.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+		}
+		ends = append(ends, s.previous().line)
+		if !s.match(TokenComma) {
+			break
+		}
+	}
+	closing, err := s.consume(TokenRightBrace, "Expect '}' after map entries.")
+	if err != nil {
+		return nil, err
+	}
+	literal := NewMapLiteral(brace, &keys, &values)
+	s.info.elements[literal] = &elementLines{ends: ends, close: closing.line}
+	return literal, nil
+}
+
+// literal makes a Literal, and remembers how it was written.
+func (s *Parser) literal(value interface{}, lexeme string) *Literal {
+	literal := NewLiteral(value)
+	s.info.literals[literal] = lexeme
+	return literal
+}
+
+// recordSpan remembers the tokens a statement was parsed from.
+func (s *Parser) recordSpan(start *Token, stmt *Stmt, err *error) {
+	if *err == nil && *stmt != nil {
+		s.info.spans[*stmt] = &span{start: start, end: s.previous()}
+	}
+}
+
 func (s *Parser) match(tokenTypes ...TokenType) bool {
 	for _, tokenType := range tokenTypes {
 		if s.check(tokenType) {
@@ -676,11 +1343,17 @@ func (s *Parser) synchronize() {
 			return
 		}
 		switch s.peek().tokenType {
+		case TokenAbstract:
 		case TokenClass:
+		case TokenConst:
+		case TokenEnum:
 		case TokenFun:
+		case TokenInterface:
+		case TokenTrait:
 		case TokenVar:
 		case TokenFor:
 		case TokenIf:
+		case TokenMatch:
 		case TokenWhile:
 		case TokenPrint:
 		case TokenReturn:
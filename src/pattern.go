@@ -0,0 +1,218 @@
+package glox
+
+import "fmt"
+
+// Patterns reuse expression nodes:
+//   - Literal is compared with the value using isEqual;
+//   - Variable binds the value to its name ("_" binds nothing);
+//   - Get is evaluated and compared with the value (e.g. an enum member);
+//   - Call matches an instance of the callee class (or one of its subclasses),
+//     and its arguments are matched against the fields named by the parameters of "init"
+//     (an instance lacking one of these fields is a runtime error);
+//   - ListLiteral matches a list with the same length element by element,
+//     unless it ends with a rest element ("...rest") binding the remaining elements;
+//   - ObjectPattern matches an instance having all the listed fields (or a map having all the keys).
+//
+// Destructuring (var [a, b] = ...; [a, b] = ...;) uses the same nodes, but the
+// pattern must match, and assignments may also target properties (Get).
+
+type MatchCase struct {
+	keyword  *Token
+	patterns *[]Expr
+	body     Stmt
+}
+
+func NewMatchCase(keyword *Token, patterns *[]Expr, body Stmt) *MatchCase {
+	return &MatchCase{
+		keyword:  keyword,
+		patterns: patterns,
+		body:     body,
+	}
+}
+
+type patternBinding struct {
+	target Expr
+	value  interface{}
+}
+
+func isWildcard(pattern Expr) bool {
+	v, ok := pattern.(*Variable)
+	return ok && v.name.lexeme == "_"
+}
+
+// isIrrefutable reports whether a pattern matches every value.
+func isIrrefutable(pattern Expr) bool {
+	_, ok := pattern.(*Variable)
+	return ok
+}
+
+func (s *Interpreter) matchPattern(pattern Expr, value interface{}, bindings *[]patternBinding) (bool, error) {
+	switch p := pattern.(type) {
+	case *Literal:
+		return s.isEqual(p.value, value)
+	case *Variable:
+		if !isWildcard(p) {
+			*bindings = append(*bindings, patternBinding{target: p, value: value})
+		}
+		return true, nil
+	case *Get:
+		expected, err := s.evaluate(p)
+		if err != nil {
+			return false, err
+		}
+		return s.isEqual(expected, value)
+	case *Call:
+		callee, err := s.evaluate(p.callee)
+		if err != nil {
+			return false, err
+		}
+		class, ok := callee.(*LoxClass)
+		if !ok {
+			return false, NewRuntimeError(p.paren, "Class pattern must name a class.")
+		}
+		instance, ok := value.(*LoxInstance)
+		if !ok || !instance.class.isSubclassOf(class) {
+			return false, nil
+		}
+		var params []*Token
+		if initializer := class.findMethod("init"); initializer != nil {
+			params = *initializer.declaration.params
+		}
+		if len(*p.arguments) != len(params) {
+			return false, NewRuntimeError(p.paren, fmt.Sprintf(
+				"Class pattern has %v sub-patterns but '%v.init' takes %v parameters.",
+				len(*p.arguments), class.name, len(params)))
+		}
+		for i, argument := range *p.arguments {
+			field, ok := (*instance.fields)[params[i].lexeme]
+			if !ok {
+				return false, NewRuntimeError(p.paren, fmt.Sprintf(
+					"'%v' has no field '%v' for the class pattern.", class.name, params[i].lexeme))
+			}
+			matched, err := s.matchPattern(argument, field, bindings)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	case *ListLiteral:
+		list, ok := value.(*LoxList)
+		if !ok {
+			return false, nil
+		}
+		elements, rest := splitRest(p)
+		if len(list.elements) < len(elements) || (rest == nil && len(list.elements) != len(elements)) {
+			return false, nil
+		}
+		for i, element := range elements {
+			matched, err := s.matchPattern(element, list.elements[i], bindings)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		if rest != nil {
+			return s.matchPattern(rest, NewLoxList(append([]interface{}{}, list.elements[len(elements):]...)), bindings)
+		}
+		return true, nil
+	case *ObjectPattern:
+		for i, key := range *p.keys {
+			field, ok := lookUpKey(value, key.lexeme)
+			if !ok {
+				return false, nil
+			}
+			matched, err := s.matchPattern((*p.values)[i], field, bindings)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// destructure is like matchPattern, except that any mismatch is a runtime error.
+func (s *Interpreter) destructure(pattern Expr, value interface{}, token *Token, bindings *[]patternBinding) error {
+	switch p := pattern.(type) {
+	case *Variable:
+		if !isWildcard(p) {
+			*bindings = append(*bindings, patternBinding{target: p, value: value})
+		}
+	case *Get:
+		*bindings = append(*bindings, patternBinding{target: p, value: value})
+	case *ListLiteral:
+		list, ok := value.(*LoxList)
+		if !ok {
+			return NewRuntimeError(token, "Only lists can be destructured with '[...]'.")
+		}
+		elements, rest := splitRest(p)
+		if rest == nil && len(list.elements) != len(elements) {
+			return NewRuntimeError(token, fmt.Sprintf(
+				"Expected a list of %v elements but got %v.", len(elements), len(list.elements)))
+		}
+		if len(list.elements) < len(elements) {
+			return NewRuntimeError(token, fmt.Sprintf(
+				"Expected a list of at least %v elements but got %v.", len(elements), len(list.elements)))
+		}
+		for i, element := range elements {
+			err := s.destructure(element, list.elements[i], token, bindings)
+			if err != nil {
+				return err
+			}
+		}
+		if rest != nil {
+			return s.destructure(rest, NewLoxList(append([]interface{}{}, list.elements[len(elements):]...)), token, bindings)
+		}
+	case *ObjectPattern:
+		if m, ok := value.(*LoxMap); ok {
+			for i, key := range *p.keys {
+				entry, ok := m.lookUp(key.lexeme)
+				if !ok {
+					return NewRuntimeError(key, "Undefined key '"+key.lexeme+"'.")
+				}
+				err := s.destructure((*p.values)[i], entry, token, bindings)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		instance, ok := value.(*LoxInstance)
+		if !ok {
+			return NewRuntimeError(token, "Only instances and maps can be destructured with '{...}'.")
+		}
+		for i, key := range *p.keys {
+			field, err := instance.get(key)
+			if err != nil {
+				return err
+			}
+			err = s.destructure((*p.values)[i], field, token, bindings)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitRest separates the trailing rest element ("...rest") of a list pattern.
+func splitRest(pattern *ListLiteral) ([]Expr, Expr) {
+	elements := *pattern.elements
+	if len(elements) != 0 {
+		if spread, ok := elements[len(elements)-1].(*Spread); ok {
+			return elements[:len(elements)-1], spread.expression
+		}
+	}
+	return elements, nil
+}
+
+// lookUpKey reads a field of an instance or a string key of a map.
+func lookUpKey(value interface{}, key string) (interface{}, bool) {
+	switch v := value.(type) {
+	case *LoxInstance:
+		field, ok := (*v.fields)[key]
+		return field, ok
+	case *LoxMap:
+		return v.lookUp(key)
+	}
+	return nil, false
+}
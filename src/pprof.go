@@ -0,0 +1,151 @@
+package glox
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// protobuf encodes the messages of a protocol buffer, which is all pprof needs.
+// https://protobuf.dev/programming-guides/encoding/
+type protobuf struct {
+	data []byte
+}
+
+func (s *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		s.data = append(s.data, byte(x)|0x80)
+		x >>= 7
+	}
+	s.data = append(s.data, byte(x))
+}
+
+// uint64Field writes a field of the varint wire type; zero values are left out.
+func (s *protobuf) uint64Field(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	s.varint(uint64(field) << 3)
+	s.varint(x)
+}
+
+func (s *protobuf) int64Field(field int, x int64) {
+	s.uint64Field(field, uint64(x))
+}
+
+// bytesField writes a field of the length-delimited wire type.
+func (s *protobuf) bytesField(field int, data []byte) {
+	s.varint(uint64(field)<<3 | 2)
+	s.varint(uint64(len(data)))
+	s.data = append(s.data, data...)
+}
+
+func (s *protobuf) stringField(field int, str string) {
+	s.bytesField(field, []byte(str))
+}
+
+func (s *protobuf) messageField(field int, message *protobuf) {
+	s.bytesField(field, message.data)
+}
+
+// packedField writes a repeated varint field.
+func (s *protobuf) packedField(field int, xs []uint64) {
+	packed := &protobuf{}
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	s.bytesField(field, packed.data)
+}
+
+// =====
+
+// writePprof writes a gzipped profile.proto, as read by "go tool pprof".
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+func (s *profiler) writePprof(output io.Writer) error {
+	table := []string{""}
+	indexes := map[string]int64{"": 0}
+	str := func(value string) int64 {
+		if index, ok := indexes[value]; ok {
+			return index
+		}
+		indexes[value] = int64(len(table))
+		table = append(table, value)
+		return indexes[value]
+	}
+	profile := &protobuf{}
+	valueType := func(field int, kind string, unit string) {
+		message := &protobuf{}
+		message.int64Field(1, str(kind))
+		message.int64Field(2, str(unit))
+		profile.messageField(field, message)
+	}
+
+	// Profile.sample_type
+	valueType(1, "calls", "count")
+	valueType(1, "time", "nanoseconds")
+
+	// Profile.sample
+	var keys []string
+	for key := range s.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sample := s.samples[key]
+		message := &protobuf{}
+		message.packedField(1, sample.stack)
+		message.packedField(2, []uint64{uint64(sample.calls), uint64(sample.time)})
+		profile.messageField(2, message)
+	}
+
+	// Profile.location, with one line each, and Profile.function
+	locations := make([]profileLocation, len(s.locations))
+	for location, id := range s.locations {
+		locations[id-1] = location
+	}
+	functions := map[*Function]uint64{}
+	var declarations []*Function
+	for i, location := range locations {
+		id, ok := functions[location.function]
+		if !ok {
+			id = uint64(len(functions) + 1)
+			functions[location.function] = id
+			declarations = append(declarations, location.function)
+		}
+		line := &protobuf{}
+		line.uint64Field(1, id)
+		line.int64Field(2, int64(location.line))
+		message := &protobuf{}
+		message.uint64Field(1, uint64(i+1))
+		message.messageField(4, line)
+		profile.messageField(4, message)
+	}
+	for i, declaration := range declarations {
+		name := s.functionName(declaration)
+		message := &protobuf{}
+		message.uint64Field(1, uint64(i+1))
+		message.int64Field(2, str(name))
+		message.int64Field(3, str(name))
+		message.int64Field(4, str(s.filename))
+		if declaration != nil {
+			message.int64Field(5, int64(declaration.name.line))
+		}
+		profile.messageField(5, message)
+	}
+
+	// Profile.time_nanos, Profile.duration_nanos and Profile.default_sample_type;
+	// the string table is written last, once every string is in it.
+	profile.int64Field(9, s.start.UnixNano())
+	profile.int64Field(10, int64(s.last.Sub(s.start)))
+	profile.int64Field(14, str("time"))
+	for _, value := range table {
+		profile.stringField(6, value)
+	}
+
+	writer := gzip.NewWriter(output)
+	_, err := writer.Write(profile.data)
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,271 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// profiler measures where a program spends its time. The time between two
+// statements is charged to the call stack running the first one, each frame
+// of the stack being a function and the line it is at, so the profile has the
+// exact time and call counts per function and per line.
+type profiler struct {
+	spans   map[Stmt]*span
+	methods map[*Function]string
+
+	filename  string
+	start     time.Time
+	last      time.Time
+	frames    []*profileFrame
+	locations map[profileLocation]uint64
+	samples   map[string]*profileSample
+	calls     map[*Function]int64
+	hits      map[int]int64
+}
+
+// profileFrame is a function being run, nil standing for the top-level code,
+// with the location it is at and the key of the stack of its callers.
+type profileFrame struct {
+	function *Function
+	location uint64
+	callers  string
+}
+
+type profileLocation struct {
+	function *Function
+	line     int
+}
+
+// profileSample is the time and calls of a stack, leaf first.
+type profileSample struct {
+	stack []uint64
+	calls int64
+	time  time.Duration
+}
+
+// The formats of the profile written by writeProfile.
+const (
+	ProfilePprof = "pprof"
+	ProfileText  = "text"
+)
+
+func newProfiler() *profiler {
+	s := &profiler{
+		spans:     map[Stmt]*span{},
+		methods:   map[*Function]string{},
+		locations: map[profileLocation]uint64{},
+		samples:   map[string]*profileSample{},
+		calls:     map[*Function]int64{},
+		hits:      map[int]int64{},
+	}
+	s.start = time.Now()
+	s.last = s.start
+	s.frames = []*profileFrame{{location: s.location(nil, 0)}}
+	return s
+}
+
+// addProgram tells the profiler the lines of the statements about to run,
+// and the classes owning the methods. The time since the previous run, like
+// the time spent parsing, isn't charged to anything.
+func (s *profiler) addProgram(info *sourceInfo, statements []Stmt) {
+	for stmt, span := range info.spans {
+		s.spans[stmt] = span
+	}
+	s.last = time.Now()
+	for _, stmt := range statements {
+		var owner string
+		var methods []*Function
+		switch t := stmt.(type) {
+		case *Class:
+			owner, methods = t.name.lexeme, *t.methods
+		case *Trait:
+			owner, methods = t.name.lexeme, *t.methods
+		}
+		for _, method := range methods {
+			s.methods[method] = owner + "." + method.name.lexeme
+		}
+	}
+}
+
+func (s *profiler) location(function *Function, line int) uint64 {
+	key := profileLocation{function, line}
+	id, ok := s.locations[key]
+	if !ok {
+		id = uint64(len(s.locations) + 1)
+		s.locations[key] = id
+	}
+	return id
+}
+
+// sample returns the sample of the current stack.
+func (s *profiler) sample() *profileSample {
+	top := s.frames[len(s.frames)-1]
+	key := top.callers + strconv.FormatUint(top.location, 10)
+	sample, ok := s.samples[key]
+	if !ok {
+		sample = &profileSample{}
+		for i := len(s.frames) - 1; i >= 0; i-- {
+			sample.stack = append(sample.stack, s.frames[i].location)
+		}
+		s.samples[key] = sample
+	}
+	return sample
+}
+
+// charge charges the time since the previous event to the current stack.
+func (s *profiler) charge() {
+	now := time.Now()
+	s.sample().time += now.Sub(s.last)
+	s.last = now
+}
+
+func (s *profiler) beforeStatement(stmt Stmt) error {
+	span, ok := s.spans[stmt]
+	if !ok {
+		return nil
+	}
+	s.charge()
+	top := s.frames[len(s.frames)-1]
+	top.location = s.location(top.function, span.start.line)
+	s.hits[span.start.line]++
+	return nil
+}
+
+func (s *profiler) enterFunction(function *LoxFunction, _ *Environment) {
+	s.charge()
+	caller := s.frames[len(s.frames)-1]
+	declaration := function.declaration
+	s.frames = append(s.frames, &profileFrame{
+		function: declaration,
+		location: s.location(declaration, declaration.name.line),
+		callers:  caller.callers + strconv.FormatUint(caller.location, 10) + "/",
+	})
+	s.sample().calls++
+	s.calls[declaration]++
+}
+
+func (s *profiler) leaveFunction() {
+	s.charge()
+	s.frames = s.frames[:len(s.frames)-1]
+}
+
+// functionName names the top-level code "[script]", as pprof takes what is
+// between angle brackets for C++ template arguments and removes it from names.
+func (s *profiler) functionName(function *Function) string {
+	if function == nil {
+		return "[script]"
+	}
+	if name, ok := s.methods[function]; ok {
+		return name
+	}
+	return function.name.lexeme
+}
+
+// =====
+
+// writeProfile writes the profile in the format.
+func (s *profiler) writeProfile(output io.Writer, format string) error {
+	s.charge()
+	switch format {
+	case ProfilePprof:
+		return s.writePprof(output)
+	case ProfileText:
+		return s.writeText(output)
+	}
+	return errors.New("Unknown profile format '" + format + "'.")
+}
+
+// functionProfile is the summary of a function: its self time is spent in
+// its own statements, and its total time includes the calls it makes.
+type functionProfile struct {
+	function *Function
+	calls    int64
+	self     time.Duration
+	total    time.Duration
+}
+
+// writeText writes the functions sorted by total time, then the lines sorted by time.
+func (s *profiler) writeText(output io.Writer) error {
+	ids := map[uint64]profileLocation{}
+	for location, id := range s.locations {
+		ids[id] = location
+	}
+	functions := map[*Function]*functionProfile{}
+	function := func(f *Function) *functionProfile {
+		if _, ok := functions[f]; !ok {
+			functions[f] = &functionProfile{function: f, calls: s.calls[f]}
+		}
+		return functions[f]
+	}
+	lines := map[int]time.Duration{}
+	for _, sample := range s.samples {
+		leaf := ids[sample.stack[0]]
+		function(leaf.function).self += sample.time
+		lines[leaf.line] += sample.time
+		seen := map[*Function]bool{}
+		for _, id := range sample.stack {
+			f := ids[id].function
+			if !seen[f] {
+				seen[f] = true
+				function(f).total += sample.time
+			}
+		}
+	}
+
+	var profiles []*functionProfile
+	for _, f := range functions {
+		profiles = append(profiles, f)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		a, b := profiles[i], profiles[j]
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return s.functionName(a.function) < s.functionName(b.function)
+	})
+	_, err := fmt.Fprintf(output, "%10v %12v %12v  %v\n", "calls", "total", "self", "function")
+	if err != nil {
+		return err
+	}
+	for _, f := range profiles {
+		name := s.functionName(f.function)
+		if f.function != nil {
+			name += fmt.Sprintf(" (line %v)", f.function.name.line)
+		}
+		_, err = fmt.Fprintf(output, "%10v %12v %12v  %v\n", f.calls, formatDuration(f.total), formatDuration(f.self), name)
+		if err != nil {
+			return err
+		}
+	}
+
+	var numbers []int
+	for line := range s.hits {
+		numbers = append(numbers, line)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		a, b := numbers[i], numbers[j]
+		if lines[a] != lines[b] {
+			return lines[a] > lines[b]
+		}
+		return a < b
+	})
+	_, err = fmt.Fprintf(output, "\n%10v %12v %12v\n", "line", "hits", "time")
+	if err != nil {
+		return err
+	}
+	for _, line := range numbers {
+		_, err = fmt.Fprintf(output, "%10v %12v %12v\n", line, s.hits[line], formatDuration(lines[line]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
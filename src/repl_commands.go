@@ -0,0 +1,195 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// replCommand is a REPL command, written as ":name argument".
+type replCommand struct {
+	usage       string
+	description string
+	run         func(s *Glox, argument string) error
+}
+
+func replCommands() map[string]*replCommand {
+	return map[string]*replCommand{
+		"help":   {":help [name]", "List the commands, or show the doc comment of a global", (*Glox).helpCommand},
+		"env":    {":env", "Show the bindings of the global and current environments", (*Glox).envCommand},
+		"ast":    {":ast <expr>", "Print the syntax tree of an expression", (*Glox).astCommand},
+		"tokens": {":tokens <source>", "Print the tokens of some source", (*Glox).tokensCommand},
+		"type":   {":type <expr>", "Print the static type of an expression", (*Glox).typeCommand},
+		"load":   {":load <file>", "Run a file in the current session, without echoing values", (*Glox).loadCommand},
+		"reset":  {":reset", "Start over with a fresh interpreter", (*Glox).resetCommand},
+		"save":   {":save <file>", "Write the inputs accepted so far to a file", (*Glox).saveCommand},
+		"time":   {":time <stmt>", "Run a statement and print how long it took", (*Glox).timeCommand},
+	}
+}
+
+// runCommand runs a line starting with ":".
+func (s *Glox) runCommand(line string) {
+	line = strings.TrimPrefix(strings.TrimSpace(line), ":")
+	name, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+	command, ok := replCommands()[name]
+	if !ok {
+		_, _ = fmt.Fprintln(s.errorOutput, "[Command]", "Unknown command ':"+name+"', see ':help'.")
+		return
+	}
+	err := command.run(s, argument)
+	if err != nil {
+		_, _ = fmt.Fprintln(s.errorOutput, "[Command]", err.Error())
+	}
+}
+
+func (s *Glox) helpCommand(argument string) error {
+	if argument != "" {
+		value, ok := s.interpreter.globals.values[argument]
+		if !ok {
+			return fmt.Errorf("Undefined global '%v'.", argument)
+		}
+		doc := ""
+		switch t := value.(type) {
+		case *LoxFunction:
+			doc = t.declaration.doc
+		case *LoxClass:
+			doc = t.declaration.doc
+		}
+		if doc == "" {
+			return fmt.Errorf("No documentation for '%v'.", argument)
+		}
+		_, _ = fmt.Fprintln(s.output, doc)
+		return nil
+	}
+	commands := replCommands()
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(s.output, "%-18v %v\n", commands[name].usage, commands[name].description)
+	}
+	return nil
+}
+
+func (s *Glox) envCommand(_ string) error {
+	for environment := s.interpreter.environment; environment != s.interpreter.globals; environment = environment.enclosing {
+		_, _ = fmt.Fprintln(s.output, "[Scope]")
+		s.printBindings(environment)
+	}
+	_, _ = fmt.Fprintln(s.output, "[Globals]")
+	s.printBindings(s.interpreter.globals)
+	return nil
+}
+
+func (s *Glox) printBindings(environment *Environment) {
+	var names []string
+	for name := range environment.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kind := "var"
+		if environment.constants[name] {
+			kind = "const"
+		}
+		_, _ = fmt.Fprintf(s.output, "%v %v = %v\n", kind, name, s.interpreter.Stringify(environment.values[name]))
+	}
+}
+
+func (s *Glox) astCommand(argument string) error {
+	expr, err := s.parseExpression(argument)
+	if err != nil {
+		return err
+	}
+	str, err := NewAstPrinter().PrintExpression(expr)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(s.output, str)
+	return nil
+}
+
+func (s *Glox) tokensCommand(argument string) error {
+	tokens, err := NewScanner(s.tokenMap, argument).ScanTokens()
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens[:len(tokens)-1] {
+		if token.literal != nil {
+			_, _ = fmt.Fprintf(s.output, "%v:%v %v (%v)\n", token.line, token.column, token.lexeme, token.literal)
+		} else {
+			_, _ = fmt.Fprintf(s.output, "%v:%v %v\n", token.line, token.column, token.lexeme)
+		}
+	}
+	return nil
+}
+
+// typeCommand uses the type checker of the session, so it knows the
+// declarations checked so far.
+func (s *Glox) typeCommand(argument string) error {
+	expr, err := s.parseExpression(argument)
+	if err != nil {
+		return err
+	}
+	t, err := s.typeChecker.checkExpression(expr)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(s.output, t.String())
+	return nil
+}
+
+func (s *Glox) loadCommand(argument string) error {
+	fileData, err := os.ReadFile(argument)
+	if err != nil {
+		return err
+	}
+	if s.run(string(fileData), false) == 0 {
+		s.session = append(s.session, string(fileData))
+	}
+	return nil
+}
+
+func (s *Glox) resetCommand(_ string) error {
+	s.interpreter = NewInterpreter()
+	s.interpreter.output = s.output
+	if s.profiler != nil {
+		s.interpreter.tracer = s.profiler
+	}
+	s.typeChecker = NewTypeChecker()
+	s.session = nil
+	return nil
+}
+
+func (s *Glox) saveCommand(argument string) error {
+	if argument == "" {
+		return errors.New("Expect a file name after ':save'.")
+	}
+	return os.WriteFile(argument, []byte(strings.Join(s.session, "")), 0644)
+}
+
+// timeCommand prints the time only when the statement succeeds; otherwise
+// its error is reported as for any input.
+func (s *Glox) timeCommand(argument string) error {
+	start := time.Now()
+	if s.runInput(argument+"\n") != 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintln(s.output, "[Time]", time.Since(start))
+	return nil
+}
+
+// parseExpression parses source holding a single expression.
+func (s *Glox) parseExpression(source string) (Expr, error) {
+	tokens, err := NewScanner(s.tokenMap, source).ScanTokens()
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(&tokens).ParseExpression()
+}
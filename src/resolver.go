@@ -3,19 +3,34 @@ package glox
 type Resolver struct {
 	interpreter     *Interpreter
 	scopes          *scopeStack
+	globalConstants map[string]bool
+	warnings        []*ResolverWarning
 	currentFunction FunctionType
 	currentClass    ClassType
+	classStmt       *Class
+
+	// symbols, when set, records the declaration each name refers to.
+	symbols *symbolIndex
 }
 
 func NewResolver(interpreter *Interpreter) *Resolver {
 	return &Resolver{
 		interpreter:     interpreter,
 		scopes:          NewScopeStack(),
+		globalConstants: map[string]bool{},
 		currentFunction: FNone,
 		currentClass:    CNone,
 	}
 }
 
+func (s *Resolver) Warnings() []*ResolverWarning {
+	return s.warnings
+}
+
+func (s *Resolver) warn(token *Token, message string) {
+	s.warnings = append(s.warnings, NewResolverWarning(token, message))
+}
+
 func (s *Resolver) beginScope() {
 	s.scopes.push()
 }
@@ -26,6 +41,12 @@ func (s *Resolver) endScope() {
 
 func (s *Resolver) declare(name *Token) error {
 	if s.scopes.isEmpty() {
+		if s.globalConstants[name.lexeme] {
+			return NewResolverError(name, "Can't redefine constant '"+name.lexeme+"'.")
+		}
+		if s.symbols != nil {
+			s.symbols.declareGlobal(name)
+		}
 		return nil
 	}
 	scope := s.scopes.peek()
@@ -33,6 +54,10 @@ func (s *Resolver) declare(name *Token) error {
 		return NewResolverError(name, "Already a variable with this name in this scope.")
 	}
 	(*scope)[name.lexeme] = false
+	s.scopes.setDeclaration(name)
+	if s.symbols != nil {
+		s.symbols.reference(name, name)
+	}
 	return nil
 }
 
@@ -43,6 +68,25 @@ func (s *Resolver) define(name *Token) {
 	(*(s.scopes.peek()))[name.lexeme] = true
 }
 
+func (s *Resolver) defineConstant(name *Token) {
+	if s.scopes.isEmpty() {
+		s.globalConstants[name.lexeme] = true
+		return
+	}
+	s.define(name)
+	s.scopes.markConstant(name.lexeme)
+}
+
+// isConstant reports whether the innermost visible binding of name is a constant.
+func (s *Resolver) isConstant(name *Token) bool {
+	for i := s.scopes.size() - 1; i >= 0; i-- {
+		if _, ok := (*s.scopes.get(i))[name.lexeme]; ok {
+			return s.scopes.isConstant(i, name.lexeme)
+		}
+	}
+	return s.globalConstants[name.lexeme]
+}
+
 func (s *Resolver) resolveStatements(statements *[]Stmt) error {
 	for _, stmt := range *statements {
 		err := s.resolveStatement(stmt)
@@ -74,6 +118,13 @@ func (s *Resolver) resolveFunction(function *Function, fType FunctionType) error
 		}
 		s.define(param)
 	}
+	if function.rest != nil {
+		err := s.declare(function.rest)
+		if err != nil {
+			return err
+		}
+		s.define(function.rest)
+	}
 	err := s.resolveStatements(function.body)
 	if err != nil {
 		return err
@@ -97,7 +148,9 @@ func (s *Resolver) visitBlockStmt(stmt *Block) (interface{}, error) {
 
 func (s *Resolver) visitClassStmt(stmt *Class) (interface{}, error) {
 	enclosingClass := s.currentClass
+	enclosingClassStmt := s.classStmt
 	s.currentClass = CClass
+	s.classStmt = stmt
 	err := s.declare(stmt.name)
 	if err != nil {
 		return nil, err
@@ -106,6 +159,18 @@ func (s *Resolver) visitClassStmt(stmt *Class) (interface{}, error) {
 	if stmt.superclass != nil && stmt.name.lexeme == stmt.superclass.name.lexeme {
 		return nil, NewResolverError(stmt.superclass.name, "A class can't inherit from itself.")
 	}
+	for _, iface := range *stmt.interfaces {
+		err = s.resolveExpression(iface)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, trait := range *stmt.traits {
+		err = s.resolveExpression(trait)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if stmt.superclass != nil {
 		s.currentClass = CSubclass
 		err = s.resolveExpression(stmt.superclass)
@@ -118,6 +183,12 @@ func (s *Resolver) visitClassStmt(stmt *Class) (interface{}, error) {
 	s.beginScope()
 	(*s.scopes.peek())["this"] = true
 	for _, method := range *stmt.methods {
+		if method.body == nil {
+			if method.name.lexeme == "init" {
+				return nil, NewResolverError(method.name, "An initializer can't be abstract.")
+			}
+			continue
+		}
 		declaration := FMethod
 		if method.name.lexeme == "init" {
 			declaration = FInitializer
@@ -132,6 +203,39 @@ func (s *Resolver) visitClassStmt(stmt *Class) (interface{}, error) {
 		s.endScope()
 	}
 	s.currentClass = enclosingClass
+	s.classStmt = enclosingClassStmt
+	return nil, nil
+}
+
+func (s *Resolver) visitConstStmt(stmt *Const) (interface{}, error) {
+	err := s.declare(stmt.name)
+	if err != nil {
+		return nil, err
+	}
+	err = s.resolveExpression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	s.defineConstant(stmt.name)
+	return nil, nil
+}
+
+func (s *Resolver) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	err := s.declare(stmt.name)
+	if err != nil {
+		return nil, err
+	}
+	s.define(stmt.name)
+	seen := map[string]bool{}
+	for _, member := range *stmt.members {
+		if member.lexeme == "values" {
+			return nil, NewResolverError(member, "Enum member can't be named 'values'.")
+		}
+		if seen[member.lexeme] {
+			return nil, NewResolverError(member, "Duplicate enum member '"+member.lexeme+"'.")
+		}
+		seen[member.lexeme] = true
+	}
 	return nil, nil
 }
 
@@ -166,6 +270,136 @@ func (s *Resolver) visitIfStmt(stmt *If) (interface{}, error) {
 	return nil, nil
 }
 
+func (s *Resolver) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	seen := make(map[string]bool)
+	for _, method := range *stmt.methods {
+		if seen[method.name.lexeme] {
+			return nil, NewResolverError(method.name, "Method '"+method.name.lexeme+"' is already declared in this interface.")
+		}
+		seen[method.name.lexeme] = true
+	}
+	err := s.declare(stmt.name)
+	if err != nil {
+		return nil, err
+	}
+	s.define(stmt.name)
+	return nil, nil
+}
+
+func (s *Resolver) visitMatchStmt(stmt *Match) (interface{}, error) {
+	err := s.resolveExpression(stmt.subject)
+	if err != nil {
+		return nil, err
+	}
+	exhausted := false
+	seen := map[interface{}]bool{}
+	for _, matchCase := range *stmt.cases {
+		if exhausted {
+			s.warn(matchCase.keyword, "Unreachable case: a previous case matches every value.")
+		}
+		if len(*matchCase.patterns) > 1 {
+			for _, pattern := range *matchCase.patterns {
+				if len(patternVariables(pattern)) != 0 {
+					return nil, NewResolverError(matchCase.keyword, "Can't bind variables in a case with several patterns.")
+				}
+			}
+		}
+		s.beginScope()
+		for _, pattern := range *matchCase.patterns {
+			if literal, ok := pattern.(*Literal); ok {
+				if seen[literal.value] && !exhausted {
+					s.warn(matchCase.keyword, "Unreachable pattern: "+
+						s.interpreter.Stringify(literal.value)+" is matched by a previous case.")
+				}
+				seen[literal.value] = true
+			}
+			if isIrrefutable(pattern) {
+				exhausted = true
+			}
+			err = s.resolvePattern(pattern)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = s.resolveStatement(matchCase.body)
+		if err != nil {
+			return nil, err
+		}
+		s.endScope()
+	}
+	return nil, nil
+}
+
+func (s *Resolver) resolvePattern(pattern Expr) error {
+	switch p := pattern.(type) {
+	case *Variable:
+		if isWildcard(p) {
+			return nil
+		}
+		err := s.declare(p.name)
+		if err != nil {
+			return err
+		}
+		s.define(p.name)
+	case *Get:
+		return s.resolveExpression(p)
+	case *Call:
+		err := s.resolveExpression(p.callee)
+		if err != nil {
+			return err
+		}
+		for _, argument := range *p.arguments {
+			err = s.resolvePattern(argument)
+			if err != nil {
+				return err
+			}
+		}
+	case *ListLiteral:
+		for _, element := range *p.elements {
+			err := s.resolvePattern(element)
+			if err != nil {
+				return err
+			}
+		}
+	case *ObjectPattern:
+		for _, value := range *p.values {
+			err := s.resolvePattern(value)
+			if err != nil {
+				return err
+			}
+		}
+	case *Spread:
+		return s.resolvePattern(p.expression)
+	}
+	return nil
+}
+
+// patternVariables returns the variables bound by a pattern.
+func patternVariables(pattern Expr) []*Variable {
+	var variables []*Variable
+	switch p := pattern.(type) {
+	case *Variable:
+		if !isWildcard(p) {
+			variables = append(variables, p)
+		}
+	case *Call:
+		for _, argument := range *p.arguments {
+			variables = append(variables, patternVariables(argument)...)
+		}
+	case *ListLiteral:
+		for _, element := range *p.elements {
+			variables = append(variables, patternVariables(element)...)
+		}
+	case *ObjectPattern:
+		for _, value := range *p.values {
+			variables = append(variables, patternVariables(value)...)
+		}
+	case *Spread:
+		variables = append(variables, patternVariables(p.expression)...)
+	}
+	return variables
+}
+
 func (s *Resolver) visitPrintStmt(stmt *Print) (interface{}, error) {
 	return nil, s.resolveExpression(stmt.expression)
 }
@@ -183,6 +417,31 @@ func (s *Resolver) visitReturnStmt(stmt *Return) (interface{}, error) {
 	return nil, nil
 }
 
+func (s *Resolver) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	enclosingClass := s.currentClass
+	s.currentClass = CTrait
+	err := s.declare(stmt.name)
+	if err != nil {
+		return nil, err
+	}
+	s.define(stmt.name)
+	s.beginScope()
+	(*s.scopes.peek())["this"] = true
+	for _, method := range *stmt.methods {
+		declaration := FMethod
+		if method.name.lexeme == "init" {
+			declaration = FInitializer
+		}
+		err = s.resolveFunction(method, declaration)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.endScope()
+	s.currentClass = enclosingClass
+	return nil, nil
+}
+
 func (s *Resolver) visitVarStmt(stmt *Var) (interface{}, error) {
 	err := s.declare(stmt.name)
 	if err != nil {
@@ -198,6 +457,24 @@ func (s *Resolver) visitVarStmt(stmt *Var) (interface{}, error) {
 	return nil, nil
 }
 
+func (s *Resolver) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	variables := patternVariables(stmt.pattern)
+	for _, variable := range variables {
+		err := s.declare(variable.name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := s.resolveExpression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	for _, variable := range variables {
+		s.define(variable.name)
+	}
+	return nil, nil
+}
+
 func (s *Resolver) visitWhileStmt(stmt *While) (interface{}, error) {
 	err := s.resolveExpression(stmt.condition)
 	if err != nil {
@@ -213,10 +490,63 @@ func (s *Resolver) visitAssignExpr(expr *Assign) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.isConstant(expr.name) {
+		return nil, NewResolverError(expr.name, "Can't assign to constant '"+expr.name.lexeme+"'.")
+	}
 	s.resolveLocal(expr, expr.name)
 	return nil, nil
 }
 
+func (s *Resolver) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	err := s.resolveExpression(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.resolveAssignmentTarget(expr.pattern)
+}
+
+// resolvePrivate checks that a private member is accessed through 'this'
+// inside a class, and records that class as the owner of the member.
+func (s *Resolver) resolvePrivate(expr Expr, object Expr, name *Token) error {
+	if name.tokenType != TokenPrivateIdentifier {
+		return nil
+	}
+	if _, ok := object.(*This); !ok || s.currentClass == CNone || s.currentClass == CTrait {
+		return NewResolverError(name, "Private member '"+name.lexeme+"' can only be accessed through 'this' inside its class.")
+	}
+	s.interpreter.resolvePrivate(expr, s.classStmt)
+	return nil
+}
+
+func (s *Resolver) resolveAssignmentTarget(target Expr) error {
+	switch t := target.(type) {
+	case *Variable:
+		if isWildcard(t) {
+			return nil
+		}
+		if s.isConstant(t.name) {
+			return NewResolverError(t.name, "Can't assign to constant '"+t.name.lexeme+"'.")
+		}
+		s.resolveLocal(t, t.name)
+	case *Get:
+		err := s.resolvePrivate(t, t.object, t.name)
+		if err != nil {
+			return err
+		}
+		return s.resolveExpression(t.object)
+	case *ListLiteral:
+		for _, element := range *t.elements {
+			err := s.resolveAssignmentTarget(element)
+			if err != nil {
+				return err
+			}
+		}
+	case *Spread:
+		return s.resolveAssignmentTarget(t.expression)
+	}
+	return nil
+}
+
 func (s *Resolver) visitBinaryExpr(expr *Binary) (interface{}, error) {
 	err := s.resolveExpression(expr.left)
 	if err != nil {
@@ -240,6 +570,10 @@ func (s *Resolver) visitCallExpr(expr *Call) (interface{}, error) {
 }
 
 func (s *Resolver) visitGetExpr(expr *Get) (interface{}, error) {
+	err := s.resolvePrivate(expr, expr.object, expr.name)
+	if err != nil {
+		return nil, err
+	}
 	return nil, s.resolveExpression(expr.object)
 }
 
@@ -247,6 +581,16 @@ func (s *Resolver) visitGroupingExpr(expr *Grouping) (interface{}, error) {
 	return nil, s.resolveExpression(expr.expression)
 }
 
+func (s *Resolver) visitListLiteralExpr(expr *ListLiteral) (interface{}, error) {
+	for _, element := range *expr.elements {
+		err := s.resolveExpression(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
 func (s *Resolver) visitLiteralExpr(_ *Literal) (interface{}, error) {
 	return nil, nil
 }
@@ -259,17 +603,47 @@ func (s *Resolver) visitLogicalExpr(expr *Logical) (interface{}, error) {
 	return nil, s.resolveExpression(expr.right)
 }
 
+func (s *Resolver) visitMapLiteralExpr(expr *MapLiteral) (interface{}, error) {
+	for i, key := range *expr.keys {
+		err := s.resolveExpression(key)
+		if err != nil {
+			return nil, err
+		}
+		if value := (*expr.values)[i]; value != nil {
+			err = s.resolveExpression(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Resolver) visitObjectPatternExpr(expr *ObjectPattern) (interface{}, error) {
+	return nil, NewResolverError(expr.brace, "Object patterns can only be used in destructuring and match cases.")
+}
+
 func (s *Resolver) visitSetExpr(expr *Set) (interface{}, error) {
-	err := s.resolveExpression(expr.value)
+	err := s.resolvePrivate(expr, expr.object, expr.name)
+	if err != nil {
+		return nil, err
+	}
+	err = s.resolveExpression(expr.value)
 	if err != nil {
 		return nil, err
 	}
 	return nil, s.resolveExpression(expr.object)
 }
 
+func (s *Resolver) visitSpreadExpr(expr *Spread) (interface{}, error) {
+	return nil, s.resolveExpression(expr.expression)
+}
+
 func (s *Resolver) visitSuperExpr(expr *Super) (interface{}, error) {
 	if s.currentClass == CNone {
 		return nil, NewResolverError(expr.keyword, "Can't use 'super' outside of a class.")
+	} else if s.currentClass == CTrait {
+		return nil, NewResolverError(expr.keyword, "Can't use 'super' in a trait.")
 	} else if s.currentClass != CSubclass {
 		return nil, NewResolverError(expr.keyword, "Can't use 'super' in a class with no superclass.")
 	}
@@ -303,29 +677,63 @@ func (s *Resolver) resolveLocal(expr Expr, name *Token) {
 	for i := s.scopes.size() - 1; i >= 0; i-- {
 		if _, ok := (*s.scopes.get(i))[name.lexeme]; ok {
 			s.interpreter.resolve(expr, s.scopes.size()-1-i)
+			if s.symbols != nil {
+				if declaration := s.scopes.declaration(i, name.lexeme); declaration != nil {
+					s.symbols.reference(name, declaration)
+				}
+			}
 			return
 		}
 	}
+	if s.symbols != nil {
+		s.symbols.referenceGlobal(name)
+	}
 }
 
 // =====
 
 type scopeStack struct {
-	scopes []map[string]bool
+	scopes       []map[string]bool
+	constants    []map[string]bool
+	declarations []map[string]*Token
 }
 
 func NewScopeStack() *scopeStack {
 	return &scopeStack{
-		scopes: []map[string]bool{},
+		scopes:       []map[string]bool{},
+		constants:    []map[string]bool{},
+		declarations: []map[string]*Token{},
 	}
 }
 
 func (s *scopeStack) push() {
 	s.scopes = append(s.scopes, make(map[string]bool))
+	s.constants = append(s.constants, make(map[string]bool))
+	s.declarations = append(s.declarations, make(map[string]*Token))
 }
 
 func (s *scopeStack) pop() {
 	s.scopes = s.scopes[:len(s.scopes)-1]
+	s.constants = s.constants[:len(s.constants)-1]
+	s.declarations = s.declarations[:len(s.declarations)-1]
+}
+
+func (s *scopeStack) setDeclaration(name *Token) {
+	s.declarations[len(s.declarations)-1][name.lexeme] = name
+}
+
+// declaration returns the token declaring the name in the scope i, or nil
+// for the names that aren't declared in the source, like "this".
+func (s *scopeStack) declaration(i int, name string) *Token {
+	return s.declarations[i][name]
+}
+
+func (s *scopeStack) markConstant(name string) {
+	s.constants[len(s.constants)-1][name] = true
+}
+
+func (s *scopeStack) isConstant(i int, name string) bool {
+	return s.constants[i][name]
 }
 
 func (s *scopeStack) get(i int) *map[string]bool {
@@ -361,4 +769,5 @@ const (
 	CNone ClassType = iota
 	CClass
 	CSubclass
+	CTrait
 )
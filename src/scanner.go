@@ -1,6 +1,12 @@
 package glox
 
-import "strconv"
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Scanner struct {
 	tokenMap *map[string]TokenType
@@ -9,6 +15,14 @@ type Scanner struct {
 	start    int
 	current  int
 	line     int
+	docLines []string
+	comments []*comment
+
+	// lineStart is the offset of the current line, startLine and startColumn
+	// locate the token being scanned. Columns count runes, starting at 1.
+	lineStart   int
+	startLine   int
+	startColumn int
 }
 
 func NewScanner(tokenMap *map[string]TokenType, source string) *Scanner {
@@ -23,8 +37,14 @@ func NewScanner(tokenMap *map[string]TokenType, source string) *Scanner {
 }
 
 func (s *Scanner) ScanTokens() ([]*Token, error) {
+	err := s.validateEncoding()
+	if err != nil {
+		return nil, err
+	}
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.column(s.start)
 		err := s.scanToken()
 		if err != nil {
 			return nil, err
@@ -34,6 +54,32 @@ func (s *Scanner) ScanTokens() ([]*Token, error) {
 	return s.tokens, nil
 }
 
+// validateEncoding reports the position of the first byte that isn't valid UTF-8.
+func (s *Scanner) validateEncoding() error {
+	for offset, ch := range s.source {
+		if ch == '\n' {
+			s.line++
+			s.lineStart = offset + 1
+		}
+		if ch == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s.source[offset:]); size == 1 {
+				return NewLineError(s.line, s.column(offset), "Invalid UTF-8 encoding.")
+			}
+		}
+	}
+	s.line = 1
+	s.lineStart = 0
+	return nil
+}
+
+func (s *Scanner) column(offset int) int {
+	return utf8.RuneCountInString(s.source[s.lineStart:offset]) + 1
+}
+
+func (s *Scanner) error(message string) *LineError {
+	return NewLineError(s.startLine, s.startColumn, message)
+}
+
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
@@ -49,10 +95,22 @@ func (s *Scanner) scanToken() error {
 		s.addToken(TokenLeftBrace)
 	case '}':
 		s.addToken(TokenRightBrace)
+	case '[':
+		s.addToken(TokenLeftBracket)
+	case ']':
+		s.addToken(TokenRightBracket)
+	case ':':
+		s.addToken(TokenColon)
 	case ',':
 		s.addToken(TokenComma)
 	case '.':
-		s.addToken(TokenDot)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.advance()
+			s.advance()
+			s.addToken(TokenEllipsis)
+		} else {
+			s.addToken(TokenDot)
+		}
 	case '-':
 		s.addToken(TokenMinus)
 	case '+':
@@ -71,6 +129,8 @@ func (s *Scanner) scanToken() error {
 	case '=':
 		if s.match('=') {
 			s.addToken(TokenEqualEqual)
+		} else if s.match('>') {
+			s.addToken(TokenArrow)
 		} else {
 			s.addToken(TokenEqual)
 		}
@@ -89,17 +149,31 @@ func (s *Scanner) scanToken() error {
 
 	case '/':
 		if s.match('/') {
+			isDoc := s.peek() == '/' && s.peekNext() != '/'
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			if isDoc {
+				text := s.source[s.start+3 : s.current]
+				s.docLines = append(s.docLines, strings.TrimPrefix(strings.TrimRight(text, "\r"), " "))
+			}
+			s.addComment()
+		} else if s.match('*') {
+			return s.blockComment()
 		} else {
 			s.addToken(TokenSlash)
 		}
 
-	case ' ', '\r', '\t':
+	case '#':
+		if !isIdentifierStart(s.peek()) {
+			return s.error("Expect a name after '#'.")
+		}
+		for isIdentifierPart(s.peek()) {
+			s.advance()
+		}
+		s.addToken(TokenPrivateIdentifier)
 
-	case '\n':
-		s.line++
+	case ' ', '\r', '\t', '\n':
 
 	case '"':
 		err := s.string()
@@ -109,19 +183,23 @@ func (s *Scanner) scanToken() error {
 
 	default:
 		if isDigit(ch) {
-			s.number()
-		} else if isAlpha(ch) {
+			return s.number()
+		} else if isIdentifierStart(ch) {
 			s.identifier()
 		} else {
-			return NewLineError(s.line, "Unexpected character.")
+			return s.error("Unexpected character '" + string(ch) + "'.")
 		}
 	}
 	return nil
 }
 
-func (s *Scanner) advance() byte {
-	ch := s.source[s.current]
-	s.current++
+func (s *Scanner) advance() rune {
+	ch, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	if ch == '\n' {
+		s.line++
+		s.lineStart = s.current
+	}
 	return ch
 }
 
@@ -129,36 +207,72 @@ func (s *Scanner) addToken(tokenType TokenType) {
 	s.addTokenLiteral(tokenType, nil)
 }
 
+// addTokenLiteral also attaches the pending "///" doc comment lines to the new token.
 func (s *Scanner) addTokenLiteral(tokenType TokenType, literal interface{}) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, NewToken(tokenType, text, literal, s.line))
+	token := NewToken(tokenType, text, literal, s.startLine)
+	token.column = s.startColumn
+	if s.docLines != nil {
+		token.doc = strings.Join(s.docLines, "\n")
+		s.docLines = nil
+	}
+	s.tokens = append(s.tokens, token)
 }
 
-func (s *Scanner) match(expected byte) bool {
-	if s.isAtEnd() || s.source[s.current] != expected {
+func (s *Scanner) match(expected rune) bool {
+	if s.peek() != expected {
 		return false
 	}
-	s.current++
+	s.advance()
 	return true
 }
 
-func (s *Scanner) peek() byte {
+func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current]
+	ch, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return ch
+}
+
+// blockComment skips a "/* ... */" comment, which may contain nested block comments.
+func (s *Scanner) blockComment() error {
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			return s.error("Unterminated block comment.")
+		}
+		switch {
+		case s.peek() == '/' && s.peekNext() == '*':
+			s.current += 2
+			depth++
+		case s.peek() == '*' && s.peekNext() == '/':
+			s.current += 2
+			depth--
+		default:
+			s.advance()
+		}
+	}
+	s.addComment()
+	return nil
+}
+
+// addComment keeps the comment just scanned, so that the source can be printed back with it.
+func (s *Scanner) addComment() {
+	s.comments = append(s.comments, &comment{
+		text:    strings.TrimRight(s.source[s.start:s.current], "\r"),
+		line:    s.startLine,
+		endLine: s.line,
+	})
 }
 
 func (s *Scanner) string() error {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
-		}
 		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return NewLineError(s.line, "Unexpected character.")
+		return s.error("Unterminated string.")
 	}
 
 	s.advance()
@@ -166,41 +280,133 @@ func (s *Scanner) string() error {
 	return nil
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func (s *Scanner) number() {
-	for isDigit(s.peek()) {
+// number scans integers (decimal, or hexadecimal, binary and octal with a "0x", "0b" or "0o" prefix),
+// floats, and decimals which are written with a "d" suffix (e.g. 19.99d). Digits may be separated by "_".
+func (s *Scanner) number() error {
+	base := 10
+	if s.source[s.start] == '0' {
+		switch s.peek() {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+	}
+	if base != 10 {
+		s.advance()
+		for isDigitOfBase(s.peek(), 16) || s.peek() == '_' {
+			s.advance()
+		}
+		text := s.source[s.start+2 : s.current]
+		if !validDigits(text, base) {
+			return s.error("Invalid number literal '" + s.source[s.start:s.current] + "'.")
+		}
+		value, _ := new(big.Int).SetString(strings.ReplaceAll(text, "_", ""), base)
+		s.addTokenLiteral(TokenNumber, normalizeInteger(value))
+		return nil
+	}
+
+	for isDigit(s.peek()) || s.peek() == '_' {
 		s.advance()
 	}
+	isFloat := false
 	if s.peek() == '.' && isDigit(s.peekNext()) {
+		isFloat = true
 		s.advance()
-		for isDigit(s.peek()) {
+		for isDigit(s.peek()) || s.peek() == '_' {
 			s.advance()
 		}
 	}
-	num, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
-	s.addTokenLiteral(TokenNumber, num)
+	text := s.source[s.start:s.current]
+	for _, part := range strings.Split(text, ".") {
+		if !validDigits(part, 10) {
+			return s.error("Invalid number literal '" + text + "'.")
+		}
+	}
+	text = strings.ReplaceAll(text, "_", "")
+	if s.peek() == 'd' && !isIdentifierPart(s.peekNext()) {
+		s.advance()
+		s.addTokenLiteral(TokenNumber, parseDecimal(text))
+		return nil
+	}
+	if isFloat {
+		num, _ := strconv.ParseFloat(text, 64)
+		s.addTokenLiteral(TokenNumber, num)
+		return nil
+	}
+	value, _ := new(big.Int).SetString(text, 10)
+	s.addTokenLiteral(TokenNumber, normalizeInteger(value))
+	return nil
 }
 
-func (s *Scanner) peekNext() byte {
-	if s.current+1 >= len(s.source) {
+func isDigitOfBase(ch rune, base int) bool {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return int(ch-'0') < base
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a')+10 < base
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A')+10 < base
+	}
+	return false
+}
+
+// validDigits checks that digits are not empty, belong to the base, and that every "_" is between two digits.
+func validDigits(digits string, base int) bool {
+	if digits == "" {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '_' {
+			if i == 0 || i == len(digits)-1 || digits[i-1] == '_' {
+				return false
+			}
+		} else if !isDigitOfBase(rune(digits[i]), base) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scanner) peekNext() rune {
+	if s.isAtEnd() {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
 		return 0
 	}
-	return s.source[s.current+1]
+	ch, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return ch
 }
 
-func isAlpha(ch byte) bool {
-	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch == '_')
+// isIdentifierStart and isIdentifierPart follow the Unicode XID_Start and
+// XID_Continue properties, with "_" also allowed to start an identifier.
+func isIdentifierStart(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch == '_')
+	}
+	return unicode.In(ch, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
-func iSAlphaNumeric(ch byte) bool {
-	return isDigit(ch) || isAlpha(ch)
+func isIdentifierPart(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return isDigit(ch) || isIdentifierStart(ch)
+	}
+	return isIdentifierStart(ch) ||
+		unicode.In(ch, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+			!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
 func (s *Scanner) identifier() {
-	for iSAlphaNumeric(s.peek()) {
+	for isIdentifierPart(s.peek()) {
 		s.advance()
 	}
 
@@ -0,0 +1,64 @@
+package glox
+
+// sourceInfo keeps what the syntax tree loses about how the source was
+// written, for tools printing it back: where statements start and end, the
+// lexemes of literals, the for loops that were desugared into while loops,
+// and the lines of the elements of lists, maps and calls.
+type sourceInfo struct {
+	spans    map[Stmt]*span
+	literals map[*Literal]string
+	loops    map[Stmt]*forLoop
+	elements map[Expr]*elementLines
+}
+
+// span is the first and the last token of a statement.
+type span struct {
+	start *Token
+	end   *Token
+}
+
+// elementLines are the lines the elements of a list, a map or a call end on,
+// and the line of the closing bracket.
+type elementLines struct {
+	ends  []int
+	close int
+}
+
+type forLoop struct {
+	initializer Stmt
+	condition   Expr
+	increment   Expr
+	body        Stmt
+}
+
+func newSourceInfo() *sourceInfo {
+	return &sourceInfo{
+		spans:    map[Stmt]*span{},
+		literals: map[*Literal]string{},
+		loops:    map[Stmt]*forLoop{},
+		elements: map[Expr]*elementLines{},
+	}
+}
+
+func (s *sourceInfo) startLine(stmt Stmt) int {
+	if span, ok := s.spans[stmt]; ok {
+		return span.start.line
+	}
+	return 0
+}
+
+func (s *sourceInfo) endLine(stmt Stmt) int {
+	if span, ok := s.spans[stmt]; ok {
+		return span.end.line
+	}
+	return 0
+}
+
+// =====
+
+// comment is a comment kept by the Scanner, with the lines it starts and ends on.
+type comment struct {
+	text    string
+	line    int
+	endLine int
+}
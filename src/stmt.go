@@ -9,12 +9,18 @@ type Stmt interface {
 type stmtVisitor interface {
 	visitBlockStmt(stmt *Block) (interface{}, error)
 	visitClassStmt(stmt *Class) (interface{}, error)
+	visitConstStmt(stmt *Const) (interface{}, error)
+	visitEnumStmt(stmt *Enum) (interface{}, error)
 	visitExpressionStmt(stmt *Expression) (interface{}, error)
 	visitFunctionStmt(stmt *Function) (interface{}, error)
 	visitIfStmt(stmt *If) (interface{}, error)
+	visitInterfaceStmt(stmt *Interface) (interface{}, error)
+	visitMatchStmt(stmt *Match) (interface{}, error)
 	visitPrintStmt(stmt *Print) (interface{}, error)
 	visitReturnStmt(stmt *Return) (interface{}, error)
+	visitTraitStmt(stmt *Trait) (interface{}, error)
 	visitVarStmt(stmt *Var) (interface{}, error)
+	visitVarPatternStmt(stmt *VarPattern) (interface{}, error)
 	visitWhileStmt(stmt *While) (interface{}, error)
 }
 
@@ -35,14 +41,22 @@ func (stmt *Block) accept(visitor stmtVisitor) (interface{}, error) {
 type Class struct {
 	name       *Token
 	superclass *Variable
+	interfaces *[]*Variable
+	traits     *[]*Variable
 	methods    *[]*Function
+	abstract   *Token
+	doc        string
 }
 
-func NewClass(name *Token, superclass *Variable, methods *[]*Function) *Class {
+func NewClass(name *Token, superclass *Variable, interfaces *[]*Variable, traits *[]*Variable, methods *[]*Function, abstract *Token, doc string) *Class {
 	stmt := new(Class)
 	stmt.name = name
 	stmt.superclass = superclass
+	stmt.interfaces = interfaces
+	stmt.traits = traits
 	stmt.methods = methods
+	stmt.abstract = abstract
+	stmt.doc = doc
 	return stmt
 }
 
@@ -50,6 +64,38 @@ func (stmt *Class) accept(visitor stmtVisitor) (interface{}, error) {
 	return visitor.visitClassStmt(stmt)
 }
 
+type Const struct {
+	name        *Token
+	initializer Expr
+}
+
+func NewConst(name *Token, initializer Expr) *Const {
+	stmt := new(Const)
+	stmt.name = name
+	stmt.initializer = initializer
+	return stmt
+}
+
+func (stmt *Const) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitConstStmt(stmt)
+}
+
+type Enum struct {
+	name    *Token
+	members *[]*Token
+}
+
+func NewEnum(name *Token, members *[]*Token) *Enum {
+	stmt := new(Enum)
+	stmt.name = name
+	stmt.members = members
+	return stmt
+}
+
+func (stmt *Enum) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitEnumStmt(stmt)
+}
+
 type Expression struct {
 	expression Expr
 }
@@ -65,16 +111,24 @@ func (stmt *Expression) accept(visitor stmtVisitor) (interface{}, error) {
 }
 
 type Function struct {
-	name   *Token
-	params *[]*Token
-	body   *[]Stmt
+	name       *Token
+	params     *[]*Token
+	paramTypes *[]*Token
+	rest       *Token
+	returnType *Token
+	body       *[]Stmt
+	doc        string
 }
 
-func NewFunction(name *Token, params *[]*Token, body *[]Stmt) *Function {
+func NewFunction(name *Token, params *[]*Token, paramTypes *[]*Token, rest *Token, returnType *Token, body *[]Stmt, doc string) *Function {
 	stmt := new(Function)
 	stmt.name = name
 	stmt.params = params
+	stmt.paramTypes = paramTypes
+	stmt.rest = rest
+	stmt.returnType = returnType
 	stmt.body = body
+	stmt.doc = doc
 	return stmt
 }
 
@@ -100,6 +154,40 @@ func (stmt *If) accept(visitor stmtVisitor) (interface{}, error) {
 	return visitor.visitIfStmt(stmt)
 }
 
+type Interface struct {
+	name    *Token
+	methods *[]*Function
+}
+
+func NewInterface(name *Token, methods *[]*Function) *Interface {
+	stmt := new(Interface)
+	stmt.name = name
+	stmt.methods = methods
+	return stmt
+}
+
+func (stmt *Interface) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitInterfaceStmt(stmt)
+}
+
+type Match struct {
+	keyword *Token
+	subject Expr
+	cases   *[]*MatchCase
+}
+
+func NewMatch(keyword *Token, subject Expr, cases *[]*MatchCase) *Match {
+	stmt := new(Match)
+	stmt.keyword = keyword
+	stmt.subject = subject
+	stmt.cases = cases
+	return stmt
+}
+
+func (stmt *Match) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitMatchStmt(stmt)
+}
+
 type Print struct {
 	expression Expr
 }
@@ -130,15 +218,35 @@ func (stmt *Return) accept(visitor stmtVisitor) (interface{}, error) {
 	return visitor.visitReturnStmt(stmt)
 }
 
+type Trait struct {
+	name    *Token
+	methods *[]*Function
+}
+
+func NewTrait(name *Token, methods *[]*Function) *Trait {
+	stmt := new(Trait)
+	stmt.name = name
+	stmt.methods = methods
+	return stmt
+}
+
+func (stmt *Trait) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitTraitStmt(stmt)
+}
+
 type Var struct {
 	name        *Token
+	annotation  *Token
 	initializer Expr
+	doc         string
 }
 
-func NewVar(name *Token, initializer Expr) *Var {
+func NewVar(name *Token, annotation *Token, initializer Expr, doc string) *Var {
 	stmt := new(Var)
 	stmt.name = name
+	stmt.annotation = annotation
 	stmt.initializer = initializer
+	stmt.doc = doc
 	return stmt
 }
 
@@ -146,6 +254,24 @@ func (stmt *Var) accept(visitor stmtVisitor) (interface{}, error) {
 	return visitor.visitVarStmt(stmt)
 }
 
+type VarPattern struct {
+	keyword     *Token
+	pattern     Expr
+	initializer Expr
+}
+
+func NewVarPattern(keyword *Token, pattern Expr, initializer Expr) *VarPattern {
+	stmt := new(VarPattern)
+	stmt.keyword = keyword
+	stmt.pattern = pattern
+	stmt.initializer = initializer
+	return stmt
+}
+
+func (stmt *VarPattern) accept(visitor stmtVisitor) (interface{}, error) {
+	return visitor.visitVarPatternStmt(stmt)
+}
+
 type While struct {
 	condition Expr
 	body      Stmt
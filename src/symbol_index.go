@@ -0,0 +1,77 @@
+package glox
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// symbolIndex records, while the Resolver walks a program, the declaration
+// each name token refers to. Declarations refer to themselves. Global names
+// are linked once the whole program is resolved, since a function may use a
+// global declared after it.
+type symbolIndex struct {
+	references map[*Token]*Token
+	globals    map[string]*Token
+	pending    []*Token
+}
+
+func newSymbolIndex() *symbolIndex {
+	return &symbolIndex{
+		references: map[*Token]*Token{},
+		globals:    map[string]*Token{},
+	}
+}
+
+func (s *symbolIndex) reference(name *Token, declaration *Token) {
+	s.references[name] = declaration
+}
+
+// declareGlobal records a top-level declaration. A global declared again
+// keeps its first declaration.
+func (s *symbolIndex) declareGlobal(name *Token) {
+	if _, ok := s.globals[name.lexeme]; !ok {
+		s.globals[name.lexeme] = name
+	}
+	s.referenceGlobal(name)
+}
+
+func (s *symbolIndex) referenceGlobal(name *Token) {
+	s.pending = append(s.pending, name)
+}
+
+// link resolves the references to globals. Names with no declaration in the
+// program, like native functions, are left out.
+func (s *symbolIndex) link() {
+	for _, name := range s.pending {
+		if declaration, ok := s.globals[name.lexeme]; ok {
+			s.references[name] = declaration
+		}
+	}
+	s.pending = nil
+}
+
+// tokenAt returns the name token covering the 1-based line and column.
+func (s *symbolIndex) tokenAt(line int, column int) *Token {
+	for token := range s.references {
+		length := utf8.RuneCountInString(token.lexeme)
+		if token.line == line && token.column <= column && column <= token.column+length {
+			return token
+		}
+	}
+	return nil
+}
+
+// referencesTo returns the tokens referring to the declaration, sorted by position.
+func (s *symbolIndex) referencesTo(declaration *Token) []*Token {
+	var tokens []*Token
+	for token, target := range s.references {
+		if target == declaration {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		a, b := tokens[i], tokens[j]
+		return a.line < b.line || a.line == b.line && a.column < b.column
+	})
+	return tokens
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package glox
+
+import "errors"
+
+func isTerminal(_ int) bool {
+	return false
+}
+
+func enableRawMode(_ int) (func(), error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
@@ -0,0 +1,45 @@
+package glox
+
+import (
+	"bytes"
+	"glox/src"
+	"strings"
+	"testing"
+)
+
+const completionSource = `class A { f() {} }
+var a = A();
+a.x = 1;
+enum Color { Red, Green }
+`
+
+var completions = map[string]string{
+	"whi":        "while",
+	"cl":         "class classOf clock",
+	"Co":         "Color",
+	"a.":         "f x",
+	"a.x":        "x",
+	"Color.":     "Green Red values",
+	"Color.Red.": "name ordinal",
+	":ty":        "type",
+	"b.":         "",
+	"a.x.":       "",
+}
+
+func TestCompletion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var output bytes.Buffer
+	interpreter := glox.NewGloxIO(strings.NewReader(completionSource), &output, &output)
+	if interpreter.RunPrompt() != 0 {
+		t.Fatalf("the program failed:\n%v", output.String())
+	}
+	for prefix, expectation := range completions {
+		start, candidates := interpreter.Complete(prefix)
+		if strings.Join(candidates, " ") != expectation {
+			t.Fatalf("\nTestcase: %q\nOutput: %v\nExpect: %v", prefix, candidates, expectation)
+		}
+		if expectation != "" && !strings.HasPrefix(expectation, prefix[start:]) {
+			t.Fatalf("unexpected start %v for %q", start, prefix)
+		}
+	}
+}
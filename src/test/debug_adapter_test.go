@@ -0,0 +1,291 @@
+package glox
+
+import (
+	"bufio"
+	"fmt"
+	"glox/src"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dapClient talks to a DebugAdapter running in the same process.
+type dapClient struct {
+	t      *testing.T
+	input  io.WriteCloser
+	reader *bufio.Reader
+	seq    int
+	events []map[string]interface{}
+	done   chan error
+}
+
+func newDapClient(t *testing.T) *dapClient {
+	serverInput, clientOutput := io.Pipe()
+	clientInput, serverOutput := io.Pipe()
+	client := &dapClient{
+		t:      t,
+		input:  clientOutput,
+		reader: bufio.NewReader(clientInput),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		client.done <- glox.NewDebugAdapter(serverInput, serverOutput).Serve()
+		_ = serverOutput.Close()
+	}()
+	return client
+}
+
+// request sends a request and returns the body of its response, keeping the
+// events received meanwhile.
+func (s *dapClient) request(command string, arguments interface{}) map[string]interface{} {
+	s.seq++
+	writeFrame(s.t, s.input, map[string]interface{}{"seq": s.seq, "type": "request", "command": command, "arguments": arguments})
+	for {
+		message := readFrame(s.t, s.reader)
+		if message["type"] == "event" {
+			s.events = append(s.events, message)
+			continue
+		}
+		if message["success"] != true {
+			s.t.Fatalf("%v failed: %v", command, message["message"])
+		}
+		body, _ := message["body"].(map[string]interface{})
+		return body
+	}
+}
+
+// event waits for the next event with the name, skipping the others, and returns its body.
+func (s *dapClient) event(name string) map[string]interface{} {
+	for {
+		if len(s.events) == 0 {
+			s.events = append(s.events, readFrame(s.t, s.reader))
+		}
+		message := s.events[0]
+		s.events = s.events[1:]
+		if message["event"] == name {
+			body, _ := message["body"].(map[string]interface{})
+			return body
+		}
+	}
+}
+
+// output waits for the end of the program and returns what it printed.
+func (s *dapClient) output() string {
+	output := ""
+	for {
+		if len(s.events) == 0 {
+			s.events = append(s.events, readFrame(s.t, s.reader))
+		}
+		message := s.events[0]
+		s.events = s.events[1:]
+		switch message["event"] {
+		case "output":
+			output += message["body"].(map[string]interface{})["output"].(string)
+		case "terminated":
+			return output
+		}
+	}
+}
+
+func (s *dapClient) close() {
+	s.request("disconnect", map[string]interface{}{})
+	err := <-s.done
+	if err != nil {
+		s.t.Fatal(err.Error())
+	}
+}
+
+// stopped waits for the program to stop, checks why and where, and returns the names of the frames.
+func (s *dapClient) stopped(reason string, line int) []string {
+	event := s.event("stopped")
+	if event["reason"] != reason {
+		s.t.Fatalf("expect to stop for %v, got %v", reason, event["reason"])
+	}
+	frames := s.request("stackTrace", map[string]interface{}{"threadId": 1})["stackFrames"].([]interface{})
+	if top := frames[0].(map[string]interface{}); top["line"] != float64(line) {
+		s.t.Fatalf("expect to stop at line %v, got %v", line, top["line"])
+	}
+	var names []string
+	for _, f := range frames {
+		names = append(names, f.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+// variables returns the variables of a reference, as "name=value".
+func (s *dapClient) variables(reference interface{}) (string, map[string]interface{}) {
+	variables := s.request("variables", map[string]interface{}{"variablesReference": reference})["variables"].([]interface{})
+	var values []string
+	references := map[string]interface{}{}
+	for _, v := range variables {
+		v := v.(map[string]interface{})
+		values = append(values, v["name"].(string)+"="+v["value"].(string))
+		references[v["name"].(string)] = v["variablesReference"]
+	}
+	return strings.Join(values, " "), references
+}
+
+func (s *dapClient) evaluate(expression string, frame int) string {
+	return s.request("evaluate", map[string]interface{}{"expression": expression, "frameId": frame})["result"].(string)
+}
+
+const dapSource = `fun add(a, b) {
+    var sum = a + b;
+    return sum;
+}
+
+class Point {
+    init(x) { this.x = x; }
+}
+
+var total = 0;
+for (var i = 0; i < 3; i = i + 1) {
+    total = add(total, i);
+}
+var p = Point(total);
+print p.x;
+`
+
+func launch(t *testing.T, client *dapClient, stopOnEntry bool) string {
+	program := filepath.Join(t.TempDir(), "program.lox")
+	err := os.WriteFile(program, []byte(dapSource), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	client.request("initialize", map[string]interface{}{"adapterID": "glox"})
+	client.event("initialized")
+	client.request("launch", map[string]interface{}{"program": program, "stopOnEntry": stopOnEntry})
+	return program
+}
+
+func TestDebugAdapterBreakpoints(t *testing.T) {
+	client := newDapClient(t)
+	program := launch(t, client, false)
+	client.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": program},
+		"breakpoints": []interface{}{map[string]interface{}{"line": 3, "condition": "sum > 1"}},
+	})
+	client.request("configurationDone", nil)
+
+	// The condition only holds in the last call.
+	if names := client.stopped("breakpoint", 3); strings.Join(names, " ") != "add <script>" {
+		t.Fatalf("unexpected frames: %v", names)
+	}
+	scopes := client.request("scopes", map[string]interface{}{"frameId": 0})["scopes"].([]interface{})
+	locals := scopes[0].(map[string]interface{})
+	if values, _ := client.variables(locals["variablesReference"]); locals["name"] != "Locals" || values != "a=1 b=2 sum=3" {
+		t.Fatalf("unexpected locals: %v", values)
+	}
+	if result := client.evaluate("a * 10 + b", 0); result != "12" {
+		t.Fatalf("unexpected evaluation: %v", result)
+	}
+	if result := client.evaluate("i", 1); result != "2" {
+		t.Fatalf("unexpected evaluation in the caller: %v", result)
+	}
+
+	client.request("stepOut", map[string]interface{}{"threadId": 1})
+	client.stopped("step", 14)
+	client.request("stepIn", map[string]interface{}{"threadId": 1})
+	if names := client.stopped("step", 7); strings.Join(names, " ") != "Point.init <script>" {
+		t.Fatalf("unexpected frames: %v", names)
+	}
+	client.request("next", map[string]interface{}{"threadId": 1})
+	client.stopped("step", 15)
+
+	scopes = client.request("scopes", map[string]interface{}{"frameId": 0})["scopes"].([]interface{})
+	globals, references := client.variables(scopes[len(scopes)-1].(map[string]interface{})["variablesReference"])
+	if globals != "Point=Point add=<Function add> p=Point instance total=3" {
+		t.Fatalf("unexpected globals: %v", globals)
+	}
+	if fields, _ := client.variables(references["p"]); fields != "x=3" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	if output := client.output(); output != "[Print] 3\n" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	client.close()
+}
+
+func TestDebugAdapterEntryAndFunctionBreakpoints(t *testing.T) {
+	client := newDapClient(t)
+	launch(t, client, true)
+	client.request("setFunctionBreakpoints", map[string]interface{}{
+		"breakpoints": []interface{}{map[string]interface{}{"name": "add"}},
+	})
+	client.request("configurationDone", nil)
+	client.stopped("entry", 1)
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	client.stopped("function breakpoint", 2)
+	client.request("next", map[string]interface{}{"threadId": 1})
+	client.stopped("step", 3)
+
+	// Disconnecting terminates the paused program.
+	client.close()
+}
+
+func TestDebugAdapterBreakpointIDs(t *testing.T) {
+	client := newDapClient(t)
+	program := launch(t, client, false)
+	other := client.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": filepath.Join(filepath.Dir(program), "other.lox")},
+		"breakpoints": []interface{}{map[string]interface{}{"line": 1}},
+	})["breakpoints"].([]interface{})
+	breakpoints := client.request("setBreakpoints", map[string]interface{}{
+		"source": map[string]interface{}{"path": program},
+		"breakpoints": []interface{}{
+			map[string]interface{}{"line": 3, "condition": "sum >"},
+			map[string]interface{}{"line": 14},
+		},
+	})["breakpoints"].([]interface{})
+
+	// The ids are unique in the session, and only the bad condition is rejected.
+	var summary []string
+	for _, b := range append(other, breakpoints...) {
+		b := b.(map[string]interface{})
+		summary = append(summary, fmt.Sprintf("%v:%v", b["id"], b["verified"]))
+	}
+	if strings.Join(summary, " ") != "1:false 2:false 3:true" {
+		t.Fatalf("unexpected breakpoints: %v", summary)
+	}
+	client.request("configurationDone", nil)
+	event := client.event("stopped")
+	if ids := event["hitBreakpointIds"].([]interface{}); len(ids) != 1 || ids[0] != 3.0 {
+		t.Fatalf("unexpected stop: %v", event)
+	}
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	client.output()
+	client.close()
+}
+
+func TestDebugAdapterInvalidJSON(t *testing.T) {
+	client := newDapClient(t)
+	launch(t, client, true)
+	client.request("configurationDone", nil)
+	client.stopped("entry", 1)
+
+	_, err := fmt.Fprintf(client.input, "Content-Length: 9\r\n\r\n{not json")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for {
+		message := readFrame(t, client.reader)
+		if message["type"] == "response" {
+			if message["success"] != false {
+				t.Fatalf("expect a failed response, got %v", message)
+			}
+			break
+		}
+		client.events = append(client.events, message)
+	}
+
+	// The session goes on.
+	client.request("continue", map[string]interface{}{"threadId": 1})
+	if output := client.output(); output != "[Print] 3\n" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	client.close()
+}
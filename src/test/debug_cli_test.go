@@ -0,0 +1,73 @@
+package glox
+
+import (
+	"bytes"
+	"glox/src"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const debugCLISource = `fun add(a, b) {
+    var sum = a + b;
+    return sum;
+}
+
+var total = 0;
+for (var i = 0; i < 3; i = i + 1) {
+    total = add(total, i);
+}
+print total;
+`
+
+var debugCLISessions = map[string]string{
+	"break 3 if sum > 2\nrun\np sum\nbt\nc\nnext\nquit\n": `(glox) Breakpoint 1 at line 3
+(glox) Breakpoint 1, add at line 3
+3	    return sum;
+(glox) $1 = 3
+(glox) #0  add at line 3
+#1  <script> at line 8
+(glox) [Print] 3
+[Program exited normally]
+(glox) [Debug] The program is not being run.
+(glox) `,
+	"b add\nrun\np a + b\nfinish\nn\n\nstep\nquit\n": `(glox) Breakpoint 1 at function add
+(glox) Breakpoint 1, add at line 2
+2	    var sum = a + b;
+(glox) $1 = 0
+(glox) <script> at line 7
+7	for (var i = 0; i < 3; i = i + 1) {
+(glox) 8	    total = add(total, i);
+(glox) Breakpoint 1, add at line 2
+2	    var sum = a + b;
+(glox) 3	    return sum;
+(glox) `,
+	"watch total\nrun\nbt\nquit\n": `(glox) Watchpoint 1: total
+(glox) Watchpoint 1: total
+Old value = 0
+New value = 1
+<script> at line 7
+7	for (var i = 0; i < 3; i = i + 1) {
+(glox) #0  <script> at line 7
+(glox) `,
+	"break\nwatch 1\nfrobnicate\nquit\n": `(glox) [Debug] Expect a line or a function name after 'break'.
+(glox) [Debug] Expect a variable name after 'watch'.
+(glox) [Debug] Unknown command 'frobnicate', see 'help'.
+(glox) `,
+}
+
+func TestDebugCLI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "program.lox")
+	err := os.WriteFile(path, []byte(debugCLISource), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for commands, expectation := range debugCLISessions {
+		output := &bytes.Buffer{}
+		code := glox.NewDebugCLI(path, strings.NewReader(commands), output).Run()
+		if code != 0 || output.String() != expectation {
+			t.Fatalf("\nTestcase: %q\nOutput:\n%v\nExpect:\n%v", commands, output.String(), expectation)
+		}
+	}
+}
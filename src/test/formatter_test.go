@@ -0,0 +1,99 @@
+package glox
+
+import (
+	"glox/src"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var formatterOutput = map[string]string{
+	"var   a=1+2*3 ;":                                             "var a = 1 + 2 * 3;\n",
+	"fun f(a,b){return a+b;}":                                     "fun f(a, b) {\n    return a + b;\n}\n",
+	"if(a)print 1;else print 2;":                                  "if (a) print 1;\nelse print 2;\n",
+	"for(var i=0;i<3;i=i+1){print i;}":                            "for (var i = 0; i < 3; i = i + 1) {\n    print i;\n}\n",
+	"for(;;){}":                                                   "for (;;) {}\n",
+	"print 0x1F+1_000;":                                           "print 0x1F + 1_000;\n",
+	"var m={a:1,\"b\":2,[c]:3,...d};":                             "var m = {a: 1, \"b\": 2, [c]: 3, ...d};\n",
+	"var {x,y:[z,...w]}=p;":                                       "var {x, y: [z, ...w]} = p;\n",
+	"fun g(){return 1,2;}":                                        "fun g() {\n    return 1, 2;\n}\n",
+	"match(x){case -1,\"a\"=>print 1;case _=>{}}":                 "match (x) {\n    case -1, \"a\" => print 1;\n    case _ => {}\n}\n",
+	"abstract class A<B implements I with T{abstract f(x);g(){}}": "abstract class A < B implements I with T {\n    abstract f(x);\n    g() {}\n}\n",
+	"enum E{X,Y,}":                                                "enum E { X, Y }\n",
+	"var a:Number=1;fun h(x:String,...r):Bool{}":                  "var a: Number = 1;\nfun h(x: String, ...r): Bool {}\n",
+	"// a\nvar a = 1; // b\n\n\n/* c */\n{\n// d\n}\n":            "// a\nvar a = 1; // b\n\n/* c */\n{\n    // d\n}\n",
+	"var x = [1, // one\n 2, // two\n];":                          "var x = [\n    1, // one\n    2 // two\n];\n",
+	"var m = {\n// a\na: 1,\nb: [2, // two\n3]\n// c\n};":         "var m = {\n    // a\n    a: 1,\n    b: [\n        2, // two\n        3\n    ]\n    // c\n};\n",
+	"f(a, // first\n  b); // call":                                "f(\n    a, // first\n    b\n); // call\n",
+	"if (a) print 1; // one\nelse print 2; // two":                "if (a) print 1; // one\nelse print 2; // two\n",
+	"if (a) {\nprint 1;\n} // one\nelse {}":                       "if (a) {\n    print 1;\n} // one\nelse {}\n",
+}
+
+func TestFormatter(t *testing.T) {
+	for code, expectation := range formatterOutput {
+		output, err := glox.NewFormatter().Format(code)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if output != expectation {
+			t.Fatalf("\nTestcase: %q\nOutput: %q\nExpect: %q", code, output, expectation)
+		}
+	}
+}
+
+// TestFormatterIdempotence formats the test programs twice, checking that
+// the second pass changes nothing and that the syntax tree is unchanged.
+func TestFormatterIdempotence(t *testing.T) {
+	walkError := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.IsDir() || filepath.Ext(path) != ".lox" || strings.Contains(info.Name(), "error") {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, err := glox.NewFormatter().Format(string(source))
+		if err != nil {
+			t.Fatalf("file '%v': %v", path, err)
+		}
+		twice, err := glox.NewFormatter().Format(once)
+		if err != nil {
+			t.Fatalf("file '%v': %v", path, err)
+		}
+		if once != twice {
+			t.Fatalf("file '%v' isn't formatted the same way twice:\n%v\n=====\n%v", path, once, twice)
+		}
+		if printTree(t, string(source)) != printTree(t, once) {
+			t.Fatalf("formatting file '%v' changes its syntax tree", path)
+		}
+		return nil
+	})
+	if walkError != nil {
+		t.Fatal(walkError)
+	}
+}
+
+func printTree(t *testing.T, source string) string {
+	tokens, err := glox.NewScanner(glox.NewTokenMap(), source).ScanTokens()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	statements, err := glox.NewParser(&tokens).Parse()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	res := ""
+	printer := glox.NewAstPrinter()
+	for _, statement := range statements {
+		str, err := printer.PrintStatement(statement)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		res += str + "\n"
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package glox
+
+import (
+	"glox/src"
+	"testing"
+)
+
+// incompleteInputs tells whether the REPL waits for more lines after each input.
+var incompleteInputs = map[string]bool{
+	"print 1;":           false,
+	"{":                  true,
+	"fun f(a) {\n":       true,
+	"print (1 + (2":      true,
+	"var l = [1, 2":      true,
+	"f(1, [2, 3]":        true,
+	"{ print 1; }":       false,
+	"print \"abc":        true,
+	"print \"a\nb":       true,
+	"/* a comment":       true,
+	"/* a\n/* nested */": true,
+	"/// Greets.\n":      true,
+	"var x =":            true,
+	"if (x)":             true,
+	"class A < ":         true,
+	"print 1 +;":         false,
+	")":                  false,
+	"print 1; }":         false,
+	"var 1 = 2;":         false,
+	"print @;":           false,
+	"print \"a\" \"b\";": false,
+}
+
+func TestIsIncomplete(t *testing.T) {
+	for input, expectation := range incompleteInputs {
+		if glox.NewGlox().IsIncomplete(input) != expectation {
+			t.Fatalf("\nTestcase: %q\nExpect incomplete: %v", input, expectation)
+		}
+	}
+}
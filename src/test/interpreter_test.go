@@ -40,6 +40,31 @@ func initExpressionValueTestCase() {
 		"\"123\" == 123":   "false",
 		"\"nil\" != nil":   "true",
 		"nil == nil":       "true",
+
+		// numbers
+		"7 / 2":                                  "3.5",
+		"8 / 2":                                  "4",
+		"typeOf(8 / 2)":                          "float",
+		"div(7, 2)":                              "3",
+		"div(-7, 2)":                             "-3",
+		"typeOf(div(8, 2))":                      "integer",
+		"div(9223372036854775808 * 3, 3)":        "9223372036854775808",
+		"9007199254740993 == 9007199254740992.0": "false",
+		"9007199254740992 == 9007199254740992.0": "true",
+		"9007199254740993 > 9007199254740992.0":  "true",
+		"7.0 / 2":                                "3.5",
+		"1 / 0":                                  "+Inf",
+		"1 == 1.0":                               "true",
+		"2.5 * 4":                                "10",
+		"0x1F + 0b1 + 0o7":                       "39",
+		"1_000 * 1_000":                          "1000000",
+		"9223372036854775807 + 1":                "9223372036854775808",
+		"0.1d + 0.2d":                            "0.3",
+		"19.99d * 3":                             "59.97",
+		"1d / 4":                                 "0.25",
+		"0.5d == 0.5":                            "false",
+		"0.1d != 0.1":                            "true",
+		"{[2d]: \"a\", [2.0]: \"b\", [2.00d]: \"c\"}": "{2: c, 2: b}",
 	}
 }
 
@@ -69,3 +94,33 @@ func TestInterpreter(t *testing.T) {
 		}
 	}
 }
+
+func TestInterpretEcho(t *testing.T) {
+	code := "1 + 2; var a = 1; print a; { a; } fun f() { a; } f(); a = 5;"
+	expectation := []string{"3", "nil", "5"}
+	tokens, err := glox.NewScanner(glox.NewTokenMap(), code).ScanTokens()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	statements, err := glox.NewParser(&tokens).Parse()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	interpreter := glox.NewInterpreter()
+	var echoed []string
+	err = interpreter.Interpret(&statements, func(value interface{}) error {
+		echoed = append(echoed, interpreter.Stringify(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(echoed) != len(expectation) {
+		t.Fatalf("\nTestcase: %v\nOutput: %v\nExpect: %v", code, echoed, expectation)
+	}
+	for i := range echoed {
+		if echoed[i] != expectation[i] {
+			t.Fatalf("\nTestcase: %v\nOutput: %v\nExpect: %v", code, echoed, expectation)
+		}
+	}
+}
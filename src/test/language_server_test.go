@@ -0,0 +1,343 @@
+package glox
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"glox/src"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lspClient talks to a LanguageServer running in the same process.
+type lspClient struct {
+	t             *testing.T
+	input         io.WriteCloser
+	output        *bufio.Reader
+	id            int
+	notifications []map[string]interface{}
+	done          chan error
+}
+
+func newLspClient(t *testing.T) *lspClient {
+	serverInput, clientOutput := io.Pipe()
+	clientInput, serverOutput := io.Pipe()
+	client := &lspClient{
+		t:      t,
+		input:  clientOutput,
+		output: bufio.NewReader(clientInput),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		client.done <- glox.NewLanguageServer(serverInput, serverOutput).Serve()
+		_ = serverOutput.Close()
+	}()
+	return client
+}
+
+func (s *lspClient) send(message map[string]interface{}) {
+	message["jsonrpc"] = "2.0"
+	writeFrame(s.t, s.input, message)
+}
+
+func (s *lspClient) receive() map[string]interface{} {
+	return readFrame(s.t, s.output)
+}
+
+// writeFrame writes a message preceded by its Content-Length header, as the
+// language server and the debug adapter expect.
+func writeFrame(t *testing.T, output io.Writer, message map[string]interface{}) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = fmt.Fprintf(output, "Content-Length: %v\r\n\r\n%s", len(body), body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func readFrame(t *testing.T, input *bufio.Reader) map[string]interface{} {
+	length := 0
+	for {
+		line, err := input.ReadString('\n')
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		length, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(input, body)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var message map[string]interface{}
+	err = json.Unmarshal(body, &message)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return message
+}
+
+// request sends a request and returns its response, keeping the
+// notifications received meanwhile.
+func (s *lspClient) request(method string, params interface{}) map[string]interface{} {
+	s.id++
+	s.send(map[string]interface{}{"id": s.id, "method": method, "params": params})
+	for {
+		message := s.receive()
+		if _, ok := message["id"]; ok {
+			return message
+		}
+		s.notifications = append(s.notifications, message)
+	}
+}
+
+func (s *lspClient) notify(method string, params interface{}) {
+	s.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// diagnostics waits for the next diagnostics published by the server.
+func (s *lspClient) diagnostics() []interface{} {
+	for len(s.notifications) == 0 {
+		s.notifications = append(s.notifications, s.receive())
+	}
+	message := s.notifications[0]
+	s.notifications = s.notifications[1:]
+	if message["method"] != "textDocument/publishDiagnostics" {
+		s.t.Fatalf("unexpected notification: %v", message)
+	}
+	return message["params"].(map[string]interface{})["diagnostics"].([]interface{})
+}
+
+func (s *lspClient) close() {
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	err := <-s.done
+	if err != nil {
+		s.t.Fatal(err.Error())
+	}
+}
+
+const lspSource = `/// Greets someone.
+fun greet(name) {
+    var message = "Hello, " + name;
+    return message;
+}
+
+class Animal {
+    speak() { return greet("animal"); }
+}
+
+print greet("you");
+`
+
+func textDocumentPosition(line int, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///test.lox"},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+func openDocument(client *lspClient, text string) {
+	client.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///test.lox", "languageId": "lox", "version": 1, "text": text},
+	})
+}
+
+func TestLanguageServerDiagnostics(t *testing.T) {
+	client := newLspClient(t)
+	client.request("initialize", map[string]interface{}{})
+	openDocument(client, lspSource)
+	if diagnostics := client.diagnostics(); len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///test.lox", "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "var a = 1;\nprint a +;\n"}},
+	})
+	diagnostics := client.diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expect one diagnostic, got %v", diagnostics)
+	}
+	diagnostic := diagnostics[0].(map[string]interface{})
+	start := diagnostic["range"].(map[string]interface{})["start"].(map[string]interface{})
+	if diagnostic["message"] != "Expect expression." || start["line"] != 1.0 || start["character"] != 9.0 {
+		t.Fatalf("unexpected diagnostic: %v", diagnostic)
+	}
+
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///test.lox", "version": 3},
+		"contentChanges": []interface{}{map[string]interface{}{"text": "return 1;\n"}},
+	})
+	diagnostics = client.diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].(map[string]interface{})["message"] != "Can't return from top-level code." {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	client.close()
+}
+
+func TestLanguageServerNavigation(t *testing.T) {
+	client := newLspClient(t)
+	client.request("initialize", map[string]interface{}{})
+	openDocument(client, lspSource)
+	client.diagnostics()
+
+	// The call of greet in the method refers to the function declared above.
+	definition := client.request("textDocument/definition", textDocumentPosition(7, 23))["result"]
+	expected := `{"range":{"end":{"character":9,"line":1},"start":{"character":4,"line":1}},"uri":"file:///test.lox"}`
+	if got, _ := json.Marshal(definition); string(got) != expected {
+		t.Fatalf("\nOutput: %s\nExpect: %v", got, expected)
+	}
+
+	params := textDocumentPosition(2, 30)
+	params["context"] = map[string]interface{}{"includeDeclaration": true}
+	references := client.request("textDocument/references", params)["result"].([]interface{})
+	if len(references) != 2 {
+		t.Fatalf("expect the parameter and its use, got %v", references)
+	}
+
+	params = textDocumentPosition(1, 5)
+	params["context"] = map[string]interface{}{"includeDeclaration": false}
+	references = client.request("textDocument/references", params)["result"].([]interface{})
+	if len(references) != 2 {
+		t.Fatalf("expect the two calls of greet, got %v", references)
+	}
+
+	hover := client.request("textDocument/hover", textDocumentPosition(10, 7))["result"].(map[string]interface{})
+	value := hover["contents"].(map[string]interface{})["value"]
+	if value != "```lox\nfun greet(name)\n```\n\nGreets someone." {
+		t.Fatalf("unexpected hover: %v", value)
+	}
+	if result := client.request("textDocument/hover", textDocumentPosition(10, 0))["result"]; result != nil {
+		t.Fatalf("unexpected hover on a keyword: %v", result)
+	}
+	client.close()
+}
+
+func TestLanguageServerSymbolsAndCompletion(t *testing.T) {
+	client := newLspClient(t)
+	client.request("initialize", map[string]interface{}{})
+	openDocument(client, lspSource)
+	client.diagnostics()
+
+	symbols := client.request("textDocument/documentSymbol", textDocumentPosition(0, 0))["result"].([]interface{})
+	var names []string
+	for _, symbol := range symbols {
+		symbol := symbol.(map[string]interface{})
+		names = append(names, symbol["name"].(string))
+		if children, ok := symbol["children"]; ok {
+			for _, child := range children.([]interface{}) {
+				names = append(names, symbol["name"].(string)+"."+child.(map[string]interface{})["name"].(string))
+			}
+		}
+	}
+	if strings.Join(names, " ") != "greet Animal Animal.speak" {
+		t.Fatalf("unexpected symbols: %v", names)
+	}
+
+	items := client.request("textDocument/completion", textDocumentPosition(10, 8))["result"].([]interface{})
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	if strings.Join(labels, " ") != "greet" {
+		t.Fatalf("unexpected completions: %v", labels)
+	}
+
+	response := client.request("textDocument/formatting", textDocumentPosition(0, 0))
+	if response["error"].(map[string]interface{})["code"] != -32601.0 {
+		t.Fatalf("expect an unknown method error, got %v", response)
+	}
+	client.close()
+}
+
+func TestLanguageServerPositionEncodings(t *testing.T) {
+	text := "var s = \"😀\"; var abc = 1;\nprint abc;\n"
+	for encoding, character := range map[string]float64{"utf-16": 18, "utf-32": 17} {
+		client := newLspClient(t)
+		response := client.request("initialize", map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"general": map[string]interface{}{"positionEncodings": []string{encoding}},
+			},
+		})
+		capabilities := response["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+		if capabilities["positionEncoding"] != encoding {
+			t.Fatalf("expect the %v encoding, got %v", encoding, capabilities["positionEncoding"])
+		}
+		openDocument(client, text)
+		client.diagnostics()
+
+		// Both the position asked and the range answered count in the encoding.
+		definition := client.request("textDocument/definition", textDocumentPosition(0, int(character)+1))["result"]
+		start := definition.(map[string]interface{})["range"].(map[string]interface{})["start"].(map[string]interface{})
+		if start["character"] != character {
+			t.Fatalf("expect abc at character %v in %v, got %v", character, encoding, start["character"])
+		}
+		client.close()
+	}
+}
+
+func TestLanguageServerInvalidJSON(t *testing.T) {
+	client := newLspClient(t)
+	_, err := fmt.Fprintf(client.input, "Content-Length: 9\r\n\r\n{not json")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	response := client.receive()
+	if response["id"] != nil || response["error"].(map[string]interface{})["code"] != -32700.0 {
+		t.Fatalf("expect a parse error, got %v", response)
+	}
+
+	// The server keeps serving.
+	response = client.request("initialize", map[string]interface{}{})
+	if _, ok := response["result"]; !ok {
+		t.Fatalf("unexpected response: %v", response)
+	}
+	client.close()
+}
+
+func completionLabels(client *lspClient, line int, character int) string {
+	items := client.request("textDocument/completion", textDocumentPosition(line, character))["result"].([]interface{})
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	return strings.Join(labels, " ")
+}
+
+// TestLanguageServerMemberCompletion completes after a trailing ".", which
+// doesn't parse, with the last analysis of the document that went through.
+func TestLanguageServerMemberCompletion(t *testing.T) {
+	text := "class A {\n    f() {}\n}\nclass B < A {\n    g() { return this.f(); }\n}\nclass C {\n    h() {}\n}\nvar b = B();\n"
+	client := newLspClient(t)
+	client.request("initialize", map[string]interface{}{})
+	openDocument(client, text)
+	client.diagnostics()
+
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///test.lox", "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": text + "b."}},
+	})
+	if diagnostics := client.diagnostics(); len(diagnostics) != 1 {
+		t.Fatalf("expect one diagnostic, got %v", diagnostics)
+	}
+	if labels := completionLabels(client, 10, 2); labels != "f g" {
+		t.Fatalf("unexpected completions: %v", labels)
+	}
+	if labels := completionLabels(client, 4, 22); labels != "f g" {
+		t.Fatalf("unexpected completions in the class: %v", labels)
+	}
+	symbols := client.request("textDocument/documentSymbol", textDocumentPosition(0, 0))["result"].([]interface{})
+	if len(symbols) != 4 {
+		t.Fatalf("expect the symbols of the last analysis, got %v", symbols)
+	}
+	client.close()
+}
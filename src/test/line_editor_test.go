@@ -0,0 +1,65 @@
+package glox
+
+import (
+	"bytes"
+	"glox/src"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	keyCtrlB = "\x02"
+	keyCtrlP = "\x10"
+	keyCtrlR = "\x12"
+	keyTab   = "\t"
+	keyLeft  = "\x1b[D"
+)
+
+// TestLineEditor types lines into an editor whose history is loaded from and
+// saved to the history file of a temporary home.
+func TestLineEditor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	historyPath := filepath.Join(home, ".glox_history")
+	err := os.WriteFile(historyPath, []byte("print 1;\nvar answer = 42;\nprint answer;\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interpreter := glox.NewGloxIO(strings.NewReader("var total = 0;\n"), &bytes.Buffer{}, &bytes.Buffer{})
+	interpreter.RunPrompt()
+	keys := []string{
+		keyCtrlP + "\n",
+		keyCtrlR + "ans" + keyCtrlR + "\n",
+		"pri" + keyTab + " tot" + keyTab + ";\n",
+		"ac" + keyCtrlB + "b" + keyLeft + "x\n",
+	}
+	expectations := []string{
+		"print answer;",
+		"var answer = 42;",
+		"print total;",
+		"axbc",
+	}
+	editor := glox.NewLineEditor(strings.NewReader(strings.Join(keys, "")), &bytes.Buffer{}, historyPath, interpreter.Complete)
+	for i, expectation := range expectations {
+		line, err := editor.EditLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != expectation {
+			t.Fatalf("\nTestcase: %q\nOutput: %q\nExpect: %q", keys[i], line, expectation)
+		}
+	}
+
+	// The line repeating the latest entry of the history isn't saved again.
+	data, err := os.ReadFile(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := "print 1;\nvar answer = 42;\nprint answer;\nvar answer = 42;\nprint total;\naxbc\n"
+	if string(data) != expectation {
+		t.Fatalf("\nOutput: %q\nExpect: %q", data, expectation)
+	}
+}
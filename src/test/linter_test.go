@@ -0,0 +1,61 @@
+package glox
+
+import (
+	"glox/src"
+	"strings"
+	"testing"
+)
+
+var linterRules = map[string]string{
+	"fun f(a) { var b = 1; return 0; }":                                  "unused-parameter unused-variable",
+	"fun f(_a) { var _b = 1; return _a; }":                               "",
+	"var global = 1; fun f() { var global = 2; print global; }":          "shadow",
+	"fun f() { return 1; print 2; }":                                     "unreachable",
+	"fun f(x) { if (x) return 1; else return 2; print 3; }":              "unreachable",
+	"for (var i = 0; i < 3; i = i + 1) { print i; }":                     "",
+	"fun f() { for (;;) { return 1; } }":                                 "",
+	"var a = 1; a = a;":                                                  "self-assign",
+	"class A { init() { this.x = this.x; } }":                            "self-assign",
+	"if (true) print 1; while (nil) print 2; while (true) print 3;":      "constant-condition constant-condition",
+	"class A {} var a = A(); a.init();":                                  "init-call",
+	"var a = 1; a = a; // lox-ignore":                                    "",
+	"// lox-ignore self-assign\nvar a = 1; a = a;":                       "",
+	"var a = 1; a = a; // lox-ignore shadow":                             "self-assign",
+	"{ var y = 1; // lox-ignore unused-variable\nvar z = 2; }":           "unused-variable",
+	"{\n    // lox-ignore unused-variable\n    var y = 1; var z = 2;\n}": "",
+	"match (1) { case [x] => print 1; case y => print y; }":              "unused-variable",
+}
+
+func TestLinter(t *testing.T) {
+	for code, expectation := range linterRules {
+		warnings, err := glox.NewLinter().Lint(code)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		var rules []string
+		for _, warning := range warnings {
+			rules = append(rules, warning.Rule())
+		}
+		if strings.Join(rules, " ") != expectation {
+			t.Fatalf("\nTestcase: %v\nOutput: %v\nExpect: %v", code, rules, expectation)
+		}
+	}
+}
+
+func TestLinterDisable(t *testing.T) {
+	linter := glox.NewLinter()
+	err := linter.Disable("unused-parameter")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	warnings, err := linter.Lint("fun f(a) { return 0; }")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("disabled rule still reported: %v", warnings[0].String())
+	}
+	if linter.Disable("no-such-rule") == nil {
+		t.Fatal("unknown rule should be rejected")
+	}
+}
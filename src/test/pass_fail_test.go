@@ -19,6 +19,9 @@ func TestPassFail(t *testing.T) {
 			}
 			shouldPass := !strings.Contains(info.Name(), "error")
 			interpreter := glox.NewGlox()
+			if filepath.Base(filepath.Dir(path)) == "typecheck" {
+				interpreter.EnableTypeCheck()
+			}
 			returnCode := interpreter.RunFile(path)
 			if shouldPass && returnCode != 0 {
 				t.Fatalf("file '%v' should pass, but fail", path)
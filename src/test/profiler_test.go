@@ -0,0 +1,107 @@
+package glox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"glox/src"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const profilerSource = `fun fib(n) {
+    if (n < 2) return n;
+    return fib(n - 1) + fib(n - 2);
+}
+
+class Counter {
+    init() { this.count = 0; }
+    add() { this.count = this.count + 1; }
+}
+
+var counter = Counter();
+for (var i = 0; i < 3; i = i + 1) {
+    counter.add();
+}
+print fib(5);
+`
+
+func profile(t *testing.T, format string) []byte {
+	path := filepath.Join(t.TempDir(), "program.lox")
+	err := os.WriteFile(path, []byte(profilerSource), 0644)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	loxInterpreter := glox.NewGlox()
+	loxInterpreter.EnableProfiling()
+	if code := loxInterpreter.RunFile(path); code != 0 {
+		t.Fatalf("unexpected exit code %v", code)
+	}
+	output := &bytes.Buffer{}
+	err = loxInterpreter.WriteProfile(output, format)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return output.Bytes()
+}
+
+func TestProfilerText(t *testing.T) {
+	text := string(profile(t, glox.ProfileText))
+	functions, lines, _ := strings.Cut(text, "\n\n")
+
+	// The calls of every function, the times being left out.
+	calls := map[string]string{}
+	for _, row := range strings.Split(functions, "\n")[1:] {
+		fields := strings.Fields(row)
+		calls[strings.Join(fields[3:], " ")] = fields[0]
+	}
+	expectation := map[string]string{
+		"[script]":              "0",
+		"fib (line 1)":          "15",
+		"Counter.init (line 7)": "1",
+		"Counter.add (line 8)":  "3",
+	}
+	if len(calls) != len(expectation) {
+		t.Fatalf("unexpected functions:\n%v", functions)
+	}
+	for name, count := range expectation {
+		if calls[name] != count {
+			t.Fatalf("expect %v calls of %v, got %v:\n%v", count, name, calls[name], functions)
+		}
+	}
+
+	// The hits of some lines, which count every statement run on them: line 2
+	// is hit by the 15 "if" and the 8 "return n".
+	for line, hits := range map[string]string{"2": "23", "3": "7", "8": "3", "13": "3", "15": "1"} {
+		row := regexp.MustCompile(`(?m)^\s+` + line + `\s+(\d+)\s`).FindStringSubmatch(lines)
+		if row == nil || row[1] != hits {
+			t.Fatalf("expect %v hits of line %v:\n%v", hits, line, lines)
+		}
+	}
+}
+
+func TestProfilerPprof(t *testing.T) {
+	reader, err := gzip.NewReader(bytes.NewReader(profile(t, glox.ProfilePprof)))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, name := range []string{"[script]", "fib", "Counter.init", "Counter.add", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Fatalf("expect %q in the profile", name)
+		}
+	}
+}
+
+func TestProfilerDisabled(t *testing.T) {
+	err := glox.NewGlox().WriteProfile(io.Discard, glox.ProfileText)
+	if err == nil || err.Error() != "Profiling isn't enabled." {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
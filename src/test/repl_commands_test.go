@@ -0,0 +1,101 @@
+package glox
+
+import (
+	"bytes"
+	"glox/src"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runRepl runs a REPL session on the lines, and returns what it printed
+// without the prompts and the scanner and AST traces, and its errors.
+func runRepl(t *testing.T, lines string) (string, string) {
+	t.Setenv("HOME", t.TempDir())
+	var output, errorOutput bytes.Buffer
+	glox.NewGloxIO(strings.NewReader(lines), &output, &errorOutput).RunPrompt()
+	var printed []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		for strings.HasPrefix(line, "> ") || strings.HasPrefix(line, "... ") {
+			line = strings.TrimPrefix(strings.TrimPrefix(line, "> "), "... ")
+		}
+		if line != "" && line != ">" && !strings.HasPrefix(line, "[Scanner") && !strings.HasPrefix(line, "[AST]") {
+			printed = append(printed, line)
+		}
+	}
+	return strings.Join(printed, "\n"), errorOutput.String()
+}
+
+var replSessions = map[string]string{
+	":ast 1 + 2 * -a\n":                      "(+ 1 (* 2 (- a)))",
+	":tokens var x = \"s\";\n":               "1:1 var\n1:5 x\n1:7 =\n1:9 \"s\" (s)\n1:12 ;",
+	"var z = 1;\n:type z\n":                  "Number",
+	"class A {}\n:type A()\n":                "A",
+	"var a = 1;\n:type a\n:reset\n:type a\n": "Number\nAny",
+}
+
+func TestReplCommands(t *testing.T) {
+	for lines, expectation := range replSessions {
+		output, _ := runRepl(t, lines)
+		if output != expectation {
+			t.Fatalf("\nTestcase: %q\nOutput: %q\nExpect: %q", lines, output, expectation)
+		}
+	}
+}
+
+func TestReplEnv(t *testing.T) {
+	output, _ := runRepl(t, "var a = 1;\nconst b = \"two\";\n:env\n")
+	for _, binding := range []string{"[Globals]", "var a = 1", "const b = two", "var clock = <Function clock>"} {
+		if !strings.Contains(output, binding+"\n") {
+			t.Fatalf("expect %q in:\n%v", binding, output)
+		}
+	}
+}
+
+func TestReplReset(t *testing.T) {
+	output, errors := runRepl(t, "var a = 1;\n:reset\nprint a;\n")
+	if output != "" || !strings.Contains(errors, "Undefined") {
+		t.Fatalf("expect a to be undefined after :reset, got %q and %q", output, errors)
+	}
+}
+
+func TestReplLoadAndSave(t *testing.T) {
+	directory := t.TempDir()
+	loaded := filepath.Join(directory, "loaded.lox")
+	saved := filepath.Join(directory, "saved.lox")
+	err := os.WriteFile(loaded, []byte("var loaded = 3;\nprint loaded;\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The failing input isn't saved.
+	output, _ := runRepl(t, ":load "+loaded+"\nprint loaded + 1;\nprint nil + 1;\n:save "+saved+"\n")
+	if output != "[Print] 3\n[Print] 4" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := "var loaded = 3;\nprint loaded;\nprint loaded + 1;\n"
+	if string(data) != expectation {
+		t.Fatalf("\nOutput: %q\nExpect: %q", data, expectation)
+	}
+
+	_, errors := runRepl(t, ":save\n")
+	if errors != "[Command] Expect a file name after ':save'.\n" {
+		t.Fatalf("unexpected errors: %q", errors)
+	}
+}
+
+func TestReplTime(t *testing.T) {
+	output, errors := runRepl(t, ":time print 1;\n")
+	if !strings.HasPrefix(output, "[Print] 1\n[Time] ") || errors != "" {
+		t.Fatalf("unexpected output: %q and errors %q", output, errors)
+	}
+	output, errors = runRepl(t, ":time print nil + 1;\n")
+	if output != "" || !strings.HasPrefix(errors, "[Interpreter]") {
+		t.Fatalf("expect the error instead of the time, got %q and %q", output, errors)
+	}
+}
@@ -3,6 +3,7 @@ package glox
 import (
 	"fmt"
 	"glox/src"
+	"strings"
 	"testing"
 )
 
@@ -16,6 +17,10 @@ func initLoxCodeNumTestCase() {
 		"var a = 3; a == 2;\r\n":   10,
 		"var ord = 3; 1 or 2;\r\n": 10,
 		"var ord = 3;\n1 or 2;\n":  10,
+		"/* a /* b */ c */ 1;":     3,
+		"1 /* x\n y */ + 2;":       5,
+		"/// doc\nvar a;":          4,
+		"var größe = 名前;":          6,
 	}
 }
 
@@ -41,3 +46,21 @@ func TestScanner(t *testing.T) {
 		}
 	}
 }
+
+func TestScannerErrorColumn(t *testing.T) {
+	tokenMap := glox.NewTokenMap()
+	cases := map[string]string{
+		"var naïve = 1 € 2;":         "[line 1, column 15]",
+		"var a;\nvar é = \"x\xff\";": "[line 2, column 11]",
+	}
+	for code, position := range cases {
+		scanner := glox.NewScanner(tokenMap, code)
+		_, err := scanner.ScanTokens()
+		if err == nil {
+			t.Fatalf(`scanning code "%v" should fail`, code)
+		}
+		if !strings.HasPrefix(err.Error(), position) {
+			t.Fatalf(`the error of code "%v" should start with "%v", get "%v"`, code, position, err.Error())
+		}
+	}
+}
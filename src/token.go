@@ -7,6 +7,8 @@ type Token struct {
 	lexeme    string
 	literal   interface{}
 	line      int
+	column    int
+	doc       string
 }
 
 func NewToken(tokenType TokenType, lexeme string, literal interface{}, line int) *Token {
@@ -22,6 +24,14 @@ func (t *Token) String() string {
 	return fmt.Sprintf("%v %v %v", t.tokenType, t.lexeme, t.literal)
 }
 
+// position reports the line of the token, and its column when it was scanned from source.
+func (t *Token) position() string {
+	if t.column == 0 {
+		return fmt.Sprintf("line %v", t.line)
+	}
+	return fmt.Sprintf("line %v, column %v", t.line, t.column)
+}
+
 func (t *Token) Lexeme() string {
 	return t.lexeme
 }
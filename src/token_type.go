@@ -9,8 +9,12 @@ const (
 	TokenRightParen
 	TokenLeftBrace
 	TokenRightBrace
+	TokenLeftBracket
+	TokenRightBracket
+	TokenColon
 	TokenComma
 	TokenDot
+	TokenEllipsis
 	TokenMinus
 	TokenPlus
 	TokenSemicolon
@@ -23,6 +27,7 @@ const (
 	TokenBangEqual
 	TokenEqual
 	TokenEqualEqual
+	TokenArrow
 	TokenGreater
 	TokenGreaterEqual
 	TokenLess
@@ -31,27 +36,37 @@ const (
 	// Literals.
 
 	TokenIdentifier
+	TokenPrivateIdentifier
 	TokenString
 	TokenNumber
 
 	// Keywords.
 
+	TokenAbstract
 	TokenAnd
+	TokenCase
 	TokenClass
+	TokenConst
 	TokenElse
+	TokenEnum
 	TokenFalse
 	TokenFun
 	TokenFor
 	TokenIf
+	TokenImplements
+	TokenInterface
+	TokenMatch
 	TokenNil
 	TokenOr
 	TokenPrint
 	TokenReturn
 	TokenSuper
 	TokenThis
+	TokenTrait
 	TokenTrue
 	TokenVar
 	TokenWhile
+	TokenWith
 
 	TokenEof
 )
@@ -60,22 +75,31 @@ const (
 
 func NewTokenMap() *map[string]TokenType {
 	tokenMap := map[string]TokenType{
-		"and":    TokenAnd,
-		"class":  TokenClass,
-		"else":   TokenElse,
-		"false":  TokenFalse,
-		"for":    TokenFor,
-		"fun":    TokenFun,
-		"if":     TokenIf,
-		"nil":    TokenNil,
-		"or":     TokenOr,
-		"print":  TokenPrint,
-		"return": TokenReturn,
-		"super":  TokenSuper,
-		"this":   TokenThis,
-		"true":   TokenTrue,
-		"var":    TokenVar,
-		"while":  TokenWhile,
+		"abstract":   TokenAbstract,
+		"and":        TokenAnd,
+		"case":       TokenCase,
+		"class":      TokenClass,
+		"const":      TokenConst,
+		"else":       TokenElse,
+		"enum":       TokenEnum,
+		"false":      TokenFalse,
+		"for":        TokenFor,
+		"fun":        TokenFun,
+		"if":         TokenIf,
+		"implements": TokenImplements,
+		"interface":  TokenInterface,
+		"match":      TokenMatch,
+		"nil":        TokenNil,
+		"or":         TokenOr,
+		"print":      TokenPrint,
+		"return":     TokenReturn,
+		"super":      TokenSuper,
+		"this":       TokenThis,
+		"trait":      TokenTrait,
+		"true":       TokenTrue,
+		"var":        TokenVar,
+		"while":      TokenWhile,
+		"with":       TokenWith,
 	}
 	return &tokenMap
 }
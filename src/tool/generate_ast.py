@@ -33,6 +33,8 @@ def java_to_go(types: List[str]) -> List[List[str]]:
                 t = tmp_t
             elif t[:6] == "Object":
                 t = "interface{}"
+            elif t == "String":
+                t = "string"
             go[-1].append([m, t])
     return go
 
@@ -73,7 +75,7 @@ def define_ast(base_name: str, types: List[str]):
         for eel in el[1:]:
             star = (
                 "*"
-                if eel[1] != "interface{}"
+                if eel[1] not in ("interface{}", "string")
                 and eel[1].lower() != "expr"
                 and eel[1].lower() != "stmt"
                 else ""
@@ -86,7 +88,7 @@ def define_ast(base_name: str, types: List[str]):
         for eel in el[1:]:
             star = (
                 "*"
-                if eel[1] != "interface{}"
+                if eel[1] not in ("interface{}", "string")
                 and eel[1].lower() != "expr"
                 and eel[1].lower() != "stmt"
                 else ""
@@ -141,13 +143,18 @@ def main():
         "expr",
         [
             "Assign   : Token name, Expr value",
+            "AssignPattern : Expr pattern, Token equals, Expr value",
             "Binary   : Expr left, Token operator, Expr right",
             "Call     : Expr callee, Token paren, List<Expr> arguments",
             "Get      : Expr object, Token name",
             "Grouping : Expr expression",
+            "ListLiteral : Token bracket, List<Expr> elements",
             "Literal  : Object value",
             "Logical  : Expr left, Token operator, Expr right",
+            "MapLiteral : Token brace, List<Expr> keys, List<Expr> values",
+            "ObjectPattern : Token brace, List<Token> keys, List<Expr> values",
             "Set      : Expr object, Token name, Expr value",
+            "Spread   : Token ellipsis, Expr expression",
             "Super    : Token keyword, Token method",
             "This     : Token keyword",
             "Unary    : Token operator, Expr right",
@@ -158,13 +165,19 @@ def main():
         "stmt",
         [
             "Block      : List<Stmt> statements",
-            "Class      : Token name, Expr.Variable superclass, List<Stmt.Function> methods",
+            "Class      : Token name, Expr.Variable superclass, List<Expr.Variable> interfaces, List<Expr.Variable> traits, List<Stmt.Function> methods, Token abstract, String doc",
+            "Const      : Token name, Expr initializer",
+            "Enum       : Token name, List<Token> members",
             "Expression : Expr expression",
-            "Function   : Token name, List<Token> params, List<Stmt> body",
+            "Function   : Token name, List<Token> params, List<Token> paramTypes, Token rest, Token returnType, List<Stmt> body, String doc",
             "If         : Expr condition, Stmt thenBranch, Stmt elseBranch",
+            "Interface  : Token name, List<Stmt.Function> methods",
+            "Match      : Token keyword, Expr subject, List<MatchCase> cases",
             "Print      : Expr expression",
             "Return     : Token keyword, Expr value",
-            "Var        : Token name, Expr initializer",
+            "Trait      : Token name, List<Stmt.Function> methods",
+            "Var        : Token name, Token annotation, Expr initializer, String doc",
+            "VarPattern : Token keyword, Expr pattern, Expr initializer",
             "While      : Expr condition, Stmt body",
         ],
     )
@@ -0,0 +1,710 @@
+package glox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeChecker is an optional pass run after the Resolver. It infers the types
+// of expressions from literals, annotations and declarations, and reports the
+// operations and calls that would fail at runtime. A value whose type can't be
+// known statically has the type Any, which is compatible with every type.
+type TypeChecker struct {
+	scopes        []map[string]*typeBinding
+	currentReturn loxType
+	currentClass  *classType
+}
+
+// typeBinding is the type of a variable. An annotated variable keeps its type,
+// while the type inferred for an unannotated one widens to Any as soon as a
+// value of another type is assigned to it.
+type typeBinding struct {
+	t         loxType
+	annotated bool
+}
+
+func NewTypeChecker() *TypeChecker {
+	return &TypeChecker{
+		scopes: []map[string]*typeBinding{{}},
+	}
+}
+
+func (s *TypeChecker) checkStatements(statements *[]Stmt) error {
+	for _, stmt := range *statements {
+		_, err := stmt.accept(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TypeChecker) checkExpression(expr Expr) (loxType, error) {
+	t, err := expr.accept(s)
+	if err != nil {
+		return nil, err
+	}
+	return t.(loxType), nil
+}
+
+func (s *TypeChecker) beginScope() {
+	s.scopes = append(s.scopes, map[string]*typeBinding{})
+}
+
+func (s *TypeChecker) endScope() {
+	s.scopes = s.scopes[:len(s.scopes)-1]
+}
+
+func (s *TypeChecker) declare(name *Token, t loxType) {
+	s.scopes[len(s.scopes)-1][name.lexeme] = &typeBinding{t: t, annotated: true}
+}
+
+func (s *TypeChecker) declareInferred(name *Token, t loxType) {
+	s.scopes[len(s.scopes)-1][name.lexeme] = &typeBinding{t: t}
+}
+
+func (s *TypeChecker) binding(name string) *typeBinding {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if b, ok := s.scopes[i][name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *TypeChecker) lookUp(name string) loxType {
+	if b := s.binding(name); b != nil {
+		return b.t
+	}
+	return typeAny
+}
+
+// typeNamed turns an annotation into a type; nil stands for no annotation.
+func (s *TypeChecker) typeNamed(annotation *Token) (loxType, error) {
+	if annotation == nil {
+		return typeAny, nil
+	}
+	switch t := simpleType(annotation.lexeme); t {
+	case typeAny, typeNumber, typeString, typeBool, typeNil, typeList, typeMap, typeFunction:
+		return t, nil
+	}
+	if class, ok := s.lookUp(annotation.lexeme).(*classType); ok {
+		return &instanceType{class: class}, nil
+	}
+	return nil, NewTypeError(annotation, "Unknown type '"+annotation.lexeme+"'.")
+}
+
+func (s *TypeChecker) signature(function *Function) (*functionType, error) {
+	t := &functionType{variadic: function.rest != nil}
+	for _, annotation := range *function.paramTypes {
+		param, err := s.typeNamed(annotation)
+		if err != nil {
+			return nil, err
+		}
+		t.params = append(t.params, param)
+	}
+	result, err := s.typeNamed(function.returnType)
+	if err != nil {
+		return nil, err
+	}
+	t.result = result
+	return t, nil
+}
+
+func (s *TypeChecker) checkFunction(function *Function, t *functionType) error {
+	if function.body == nil {
+		return nil
+	}
+	enclosingReturn := s.currentReturn
+	s.currentReturn = t.result
+	s.beginScope()
+	for i, param := range *function.params {
+		s.declare(param, t.params[i])
+	}
+	if function.rest != nil {
+		s.declare(function.rest, typeList)
+	}
+	err := s.checkStatements(function.body)
+	s.endScope()
+	s.currentReturn = enclosingReturn
+	return err
+}
+
+// checkArguments checks the arguments before the first spread one, whose
+// count is only known at runtime.
+func (s *TypeChecker) checkArguments(paren *Token, callee *functionType, arguments *[]Expr) error {
+	var types []loxType
+	spread := false
+	for _, argument := range *arguments {
+		t, err := s.checkExpression(argument)
+		if err != nil {
+			return err
+		}
+		if _, ok := argument.(*Spread); ok {
+			spread = true
+		}
+		if !spread {
+			types = append(types, t)
+		}
+	}
+	if !spread && (len(types) < len(callee.params) || len(types) > len(callee.params) && !callee.variadic) {
+		expected := "Expected"
+		if callee.variadic {
+			expected += " at least"
+		}
+		return NewTypeError(paren, fmt.Sprintf("%v %v arguments but got %v.", expected, len(callee.params), len(types)))
+	}
+	for i, t := range types {
+		if i < len(callee.params) && !isAssignable(callee.params[i], t) {
+			return NewTypeError(paren, fmt.Sprintf("Argument %v must be %v, got %v.", i+1, callee.params[i], t))
+		}
+	}
+	return nil
+}
+
+func (s *TypeChecker) declarePattern(pattern Expr) {
+	for _, variable := range patternVariables(pattern) {
+		s.declare(variable.name, typeAny)
+	}
+}
+
+// =====
+
+func (s *TypeChecker) visitBlockStmt(stmt *Block) (interface{}, error) {
+	s.beginScope()
+	err := s.checkStatements(stmt.statements)
+	s.endScope()
+	return nil, err
+}
+
+func (s *TypeChecker) visitClassStmt(stmt *Class) (interface{}, error) {
+	class := &classType{
+		name:     stmt.name.lexeme,
+		methods:  map[string]*functionType{},
+		abstract: stmt.abstract != nil,
+	}
+	if stmt.superclass != nil {
+		if superclass, ok := s.lookUp(stmt.superclass.name.lexeme).(*classType); ok {
+			class.superclass = superclass
+		}
+	}
+	for _, trait := range *stmt.traits {
+		if t, ok := s.lookUp(trait.name.lexeme).(*traitType); ok {
+			for name, method := range t.methods {
+				class.methods[name] = method
+			}
+		}
+	}
+	s.declare(stmt.name, class)
+	for _, method := range *stmt.methods {
+		t, err := s.signature(method)
+		if err != nil {
+			return nil, err
+		}
+		if method.name.lexeme == "init" {
+			t.result = &instanceType{class: class}
+		}
+		class.methods[method.name.lexeme] = t
+	}
+	enclosingClass := s.currentClass
+	s.currentClass = class
+	defer func() { s.currentClass = enclosingClass }()
+	for _, method := range *stmt.methods {
+		err := s.checkFunction(method, class.methods[method.name.lexeme])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *TypeChecker) visitConstStmt(stmt *Const) (interface{}, error) {
+	t, err := s.checkExpression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	s.declare(stmt.name, t)
+	return nil, nil
+}
+
+func (s *TypeChecker) visitEnumStmt(stmt *Enum) (interface{}, error) {
+	s.declare(stmt.name, typeAny)
+	return nil, nil
+}
+
+func (s *TypeChecker) visitExpressionStmt(stmt *Expression) (interface{}, error) {
+	_, err := s.checkExpression(stmt.expression)
+	return nil, err
+}
+
+func (s *TypeChecker) visitFunctionStmt(stmt *Function) (interface{}, error) {
+	t, err := s.signature(stmt)
+	if err != nil {
+		return nil, err
+	}
+	s.declare(stmt.name, t)
+	return nil, s.checkFunction(stmt, t)
+}
+
+func (s *TypeChecker) visitIfStmt(stmt *If) (interface{}, error) {
+	_, err := s.checkExpression(stmt.condition)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stmt.thenBranch.accept(s)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.elseBranch != nil {
+		_, err = stmt.elseBranch.accept(s)
+	}
+	return nil, err
+}
+
+func (s *TypeChecker) visitInterfaceStmt(stmt *Interface) (interface{}, error) {
+	s.declare(stmt.name, typeAny)
+	return nil, nil
+}
+
+func (s *TypeChecker) visitMatchStmt(stmt *Match) (interface{}, error) {
+	_, err := s.checkExpression(stmt.subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, matchCase := range *stmt.cases {
+		s.beginScope()
+		for _, pattern := range *matchCase.patterns {
+			s.declarePattern(pattern)
+		}
+		_, err = matchCase.body.accept(s)
+		s.endScope()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *TypeChecker) visitPrintStmt(stmt *Print) (interface{}, error) {
+	_, err := s.checkExpression(stmt.expression)
+	return nil, err
+}
+
+func (s *TypeChecker) visitReturnStmt(stmt *Return) (interface{}, error) {
+	var t loxType = typeNil
+	if stmt.value != nil {
+		var err error
+		t, err = s.checkExpression(stmt.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.currentReturn != nil && !isAssignable(s.currentReturn, t) {
+		return nil, NewTypeError(stmt.keyword, fmt.Sprintf("Can't return %v from a function returning %v.", t, s.currentReturn))
+	}
+	return nil, nil
+}
+
+func (s *TypeChecker) visitTraitStmt(stmt *Trait) (interface{}, error) {
+	trait := &traitType{methods: map[string]*functionType{}}
+	for _, method := range *stmt.methods {
+		t, err := s.signature(method)
+		if err != nil {
+			return nil, err
+		}
+		trait.methods[method.name.lexeme] = t
+	}
+	s.declare(stmt.name, trait)
+	enclosingClass := s.currentClass
+	s.currentClass = nil
+	defer func() { s.currentClass = enclosingClass }()
+	for _, method := range *stmt.methods {
+		err := s.checkFunction(method, trait.methods[method.name.lexeme])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *TypeChecker) visitVarStmt(stmt *Var) (interface{}, error) {
+	declared, err := s.typeNamed(stmt.annotation)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.initializer != nil {
+		t, err := s.checkExpression(stmt.initializer)
+		if err != nil {
+			return nil, err
+		}
+		if stmt.annotation == nil {
+			if t != typeNil {
+				declared = t
+			}
+		} else if !isAssignable(declared, t) {
+			return nil, NewTypeError(stmt.name, fmt.Sprintf("Can't initialize '%v' of type %v with %v.", stmt.name.lexeme, declared, t))
+		}
+	}
+	if stmt.annotation == nil {
+		s.declareInferred(stmt.name, declared)
+	} else {
+		s.declare(stmt.name, declared)
+	}
+	return nil, nil
+}
+
+func (s *TypeChecker) visitVarPatternStmt(stmt *VarPattern) (interface{}, error) {
+	_, err := s.checkExpression(stmt.initializer)
+	if err != nil {
+		return nil, err
+	}
+	s.declarePattern(stmt.pattern)
+	return nil, nil
+}
+
+func (s *TypeChecker) visitWhileStmt(stmt *While) (interface{}, error) {
+	_, err := s.checkExpression(stmt.condition)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stmt.body.accept(s)
+	return nil, err
+}
+
+// =====
+
+func (s *TypeChecker) visitAssignExpr(expr *Assign) (interface{}, error) {
+	t, err := s.checkExpression(expr.value)
+	if err != nil {
+		return nil, err
+	}
+	b := s.binding(expr.name.lexeme)
+	if b == nil || isAssignable(b.t, t) && (b.annotated || t == typeNil || sameType(b.t, t)) {
+		return t, nil
+	}
+	if b.annotated {
+		return nil, NewTypeError(expr.name, fmt.Sprintf("Can't assign %v to '%v' of type %v.", t, expr.name.lexeme, b.t))
+	}
+	b.t = typeAny
+	return t, nil
+}
+
+func (s *TypeChecker) visitAssignPatternExpr(expr *AssignPattern) (interface{}, error) {
+	return s.checkExpression(expr.value)
+}
+
+func (s *TypeChecker) visitBinaryExpr(expr *Binary) (interface{}, error) {
+	left, err := s.checkExpression(expr.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.checkExpression(expr.right)
+	if err != nil {
+		return nil, err
+	}
+	switch expr.operator.tokenType {
+	case TokenEqualEqual, TokenBangEqual:
+		return typeBool, nil
+	case TokenPlus:
+		if left == typeAny || right == typeAny {
+			return typeAny, nil
+		}
+		if left == right && (left == typeNumber || left == typeString) {
+			return left, nil
+		}
+		return nil, NewTypeError(expr.operator, fmt.Sprintf("Operands must be two numbers or two strings, got %v and %v.", left, right))
+	}
+	if !isAssignable(typeNumber, left) || !isAssignable(typeNumber, right) || left == typeNil || right == typeNil {
+		return nil, NewTypeError(expr.operator, fmt.Sprintf("Operands must be numbers, got %v and %v.", left, right))
+	}
+	switch expr.operator.tokenType {
+	case TokenGreater, TokenGreaterEqual, TokenLess, TokenLessEqual:
+		return typeBool, nil
+	}
+	return typeNumber, nil
+}
+
+func (s *TypeChecker) visitCallExpr(expr *Call) (interface{}, error) {
+	callee, err := s.checkExpression(expr.callee)
+	if err != nil {
+		return nil, err
+	}
+	switch t := callee.(type) {
+	case *functionType:
+		return t.result, s.checkArguments(expr.paren, t, expr.arguments)
+	case *classType:
+		if t.abstract {
+			return nil, NewTypeError(expr.paren, "Can't instantiate abstract class '"+t.name+"'.")
+		}
+		initializer := t.findMethod("init")
+		if initializer == nil {
+			initializer = &functionType{}
+		}
+		return &instanceType{class: t}, s.checkArguments(expr.paren, initializer, expr.arguments)
+	case simpleType:
+		if t == typeAny || t == typeFunction {
+			return typeAny, s.checkArguments(expr.paren, &functionType{variadic: true}, expr.arguments)
+		}
+	}
+	return nil, NewTypeError(expr.paren, "Can only call functions and classes, got "+callee.String()+".")
+}
+
+func (s *TypeChecker) visitGetExpr(expr *Get) (interface{}, error) {
+	object, err := s.checkExpression(expr.object)
+	if err != nil {
+		return nil, err
+	}
+	switch t := object.(type) {
+	case *instanceType:
+		if method := t.class.findMethod(expr.name.lexeme); method != nil {
+			return method, nil
+		}
+		return typeAny, nil
+	case simpleType:
+		if t != typeAny {
+			return nil, NewTypeError(expr.name, "Only instances have properties, got "+t.String()+".")
+		}
+	}
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitGroupingExpr(expr *Grouping) (interface{}, error) {
+	return s.checkExpression(expr.expression)
+}
+
+func (s *TypeChecker) visitListLiteralExpr(expr *ListLiteral) (interface{}, error) {
+	for _, element := range *expr.elements {
+		_, err := s.checkExpression(element)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return typeList, nil
+}
+
+func (s *TypeChecker) visitLiteralExpr(expr *Literal) (interface{}, error) {
+	switch {
+	case expr.value == nil:
+		return typeNil, nil
+	case isBool(expr.value):
+		return typeBool, nil
+	case isString(expr.value):
+		return typeString, nil
+	case isNumber(expr.value):
+		return typeNumber, nil
+	}
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitLogicalExpr(expr *Logical) (interface{}, error) {
+	left, err := s.checkExpression(expr.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.checkExpression(expr.right)
+	if err != nil {
+		return nil, err
+	}
+	if left == right {
+		return left, nil
+	}
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitMapLiteralExpr(expr *MapLiteral) (interface{}, error) {
+	for i, key := range *expr.keys {
+		_, err := s.checkExpression(key)
+		if err != nil {
+			return nil, err
+		}
+		if value := (*expr.values)[i]; value != nil {
+			_, err = s.checkExpression(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return typeMap, nil
+}
+
+func (s *TypeChecker) visitObjectPatternExpr(_ *ObjectPattern) (interface{}, error) {
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitSetExpr(expr *Set) (interface{}, error) {
+	object, err := s.checkExpression(expr.object)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := object.(simpleType); ok && t != typeAny {
+		return nil, NewTypeError(expr.name, "Only instances have fields, got "+t.String()+".")
+	}
+	return s.checkExpression(expr.value)
+}
+
+func (s *TypeChecker) visitSpreadExpr(expr *Spread) (interface{}, error) {
+	t, err := s.checkExpression(expr.expression)
+	if err != nil {
+		return nil, err
+	}
+	if !isAssignable(typeList, t) && !isAssignable(typeMap, t) || t == typeNil {
+		return nil, NewTypeError(expr.ellipsis, "Can only spread lists and maps, got "+t.String()+".")
+	}
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitSuperExpr(expr *Super) (interface{}, error) {
+	if s.currentClass != nil && s.currentClass.superclass != nil {
+		if method := s.currentClass.superclass.findMethod(expr.method.lexeme); method != nil {
+			return method, nil
+		}
+	}
+	return typeAny, nil
+}
+
+func (s *TypeChecker) visitThisExpr(_ *This) (interface{}, error) {
+	if s.currentClass == nil {
+		return typeAny, nil
+	}
+	return &instanceType{class: s.currentClass}, nil
+}
+
+func (s *TypeChecker) visitUnaryExpr(expr *Unary) (interface{}, error) {
+	right, err := s.checkExpression(expr.right)
+	if err != nil {
+		return nil, err
+	}
+	if expr.operator.tokenType == TokenBang {
+		return typeBool, nil
+	}
+	if !isAssignable(typeNumber, right) || right == typeNil {
+		return nil, NewTypeError(expr.operator, "Operand must be a number, got "+right.String()+".")
+	}
+	return typeNumber, nil
+}
+
+func (s *TypeChecker) visitVariableExpr(expr *Variable) (interface{}, error) {
+	return s.lookUp(expr.name.lexeme), nil
+}
+
+// =====
+
+type loxType interface {
+	String() string
+}
+
+type simpleType string
+
+const (
+	typeAny      simpleType = "Any"
+	typeNumber   simpleType = "Number"
+	typeString   simpleType = "String"
+	typeBool     simpleType = "Bool"
+	typeNil      simpleType = "Nil"
+	typeList     simpleType = "List"
+	typeMap      simpleType = "Map"
+	typeFunction simpleType = "Function"
+)
+
+func (s simpleType) String() string {
+	return string(s)
+}
+
+type functionType struct {
+	params   []loxType
+	variadic bool
+	result   loxType
+}
+
+func (s *functionType) String() string {
+	var params []string
+	for _, param := range s.params {
+		params = append(params, param.String())
+	}
+	if s.variadic {
+		params = append(params, "...List")
+	}
+	return "fun(" + strings.Join(params, ", ") + "): " + s.result.String()
+}
+
+// classType is the type of a class; its instances have an instanceType.
+type classType struct {
+	name       string
+	superclass *classType
+	methods    map[string]*functionType
+	abstract   bool
+}
+
+func (s *classType) findMethod(name string) *functionType {
+	for c := s; c != nil; c = c.superclass {
+		if method, ok := c.methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (s *classType) isSubclassOf(class *classType) bool {
+	for c := s; c != nil; c = c.superclass {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *classType) String() string {
+	return "class " + s.name
+}
+
+type instanceType struct {
+	class *classType
+}
+
+func (s *instanceType) String() string {
+	return s.class.name
+}
+
+type traitType struct {
+	methods map[string]*functionType
+}
+
+func (s *traitType) String() string {
+	return "trait"
+}
+
+func sameType(a loxType, b loxType) bool {
+	if x, ok := a.(*instanceType); ok {
+		y, ok := b.(*instanceType)
+		return ok && x.class == y.class
+	}
+	return a == b
+}
+
+// isAssignable reports whether a value of type value may be stored where type target is expected.
+// Any is compatible with every type, and nil may be stored anywhere.
+func isAssignable(target loxType, value loxType) bool {
+	if target == typeAny || value == typeAny || value == typeNil {
+		return true
+	}
+	switch t := target.(type) {
+	case simpleType:
+		if t == typeFunction {
+			switch value.(type) {
+			case *functionType, *classType:
+				return true
+			}
+		}
+		return value == t
+	case *instanceType:
+		v, ok := value.(*instanceType)
+		return ok && v.class.isSubclassOf(t.class)
+	case *classType:
+		v, ok := value.(*classType)
+		return ok && v.isSubclassOf(t)
+	case *functionType:
+		_, ok := value.(*functionType)
+		return ok
+	}
+	return false
+}